@@ -0,0 +1,142 @@
+package builders
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"lambda-builder/ui"
+)
+
+const (
+	// ArchitectureArm64 is the lambda architecture for Graviton processors
+	ArchitectureArm64 = "arm64"
+
+	// ArchitectureX86_64 is the default lambda architecture
+	ArchitectureX86_64 = "x86_64"
+)
+
+// Architectures is the list of supported lambda architectures
+var Architectures = []string{ArchitectureArm64, ArchitectureX86_64}
+
+// NormalizeArchitecture converts an architecture or alias to its lambda name
+func NormalizeArchitecture(architecture string) (string, error) {
+	switch architecture {
+	case "arm64", "aarch64":
+		return ArchitectureArm64, nil
+	case "x86_64", "amd64":
+		return ArchitectureX86_64, nil
+	}
+
+	return "", fmt.Errorf("unsupported architecture: %s", architecture)
+}
+
+// GetArchitecture returns the lambda architecture for the build
+func (c Config) GetArchitecture() string {
+	if c.Architecture == "" {
+		return ArchitectureX86_64
+	}
+
+	return c.Architecture
+}
+
+// GetPlatform returns the container platform for the build architecture
+func (c Config) GetPlatform() string {
+	if c.GetArchitecture() == ArchitectureArm64 {
+		return "linux/arm64"
+	}
+
+	return "linux/amd64"
+}
+
+// GetBuildPlatform returns the container platform the build image is run on.
+// Builders that cross-compile for the build architecture are run on the
+// native platform of the container engine rather than under emulation
+func (c Config) GetBuildPlatform() string {
+	if c.NativeBuild {
+		return ""
+	}
+
+	return c.GetPlatform()
+}
+
+func getArchitecture(config Config) (string, error) {
+	if config.Architecture != "" {
+		return NormalizeArchitecture(config.Architecture)
+	}
+
+	lambdaYML, err := ParseLambdaYML(config)
+	if err != nil {
+		return "", err
+	}
+
+	if lambdaYML.Architecture == "" {
+		return ArchitectureX86_64, nil
+	}
+
+	return NormalizeArchitecture(lambdaYML.Architecture)
+}
+
+// checkEmulation warns when building for a foreign architecture on a
+// linux host without a binfmt handler registered for it
+func checkEmulation(logger *ui.ZerologUi, architecture string) {
+	if runtime.GOOS != "linux" {
+		return
+	}
+
+	hostArchitecture, err := getHostArchitecture()
+	if err != nil || hostArchitecture == architecture {
+		return
+	}
+
+	handler, platformArchitecture := "qemu-x86_64", "amd64"
+	if architecture == ArchitectureArm64 {
+		handler, platformArchitecture = "qemu-aarch64", "arm64"
+	}
+
+	if _, err := os.Stat(fmt.Sprintf("/proc/sys/fs/binfmt_misc/%s", handler)); err == nil {
+		return
+	}
+
+	logger.Warn(fmt.Sprintf("Building for %s on a %s host requires QEMU emulation, but no %s binfmt handler is registered", architecture, hostArchitecture, handler))
+	logger.Warn(fmt.Sprintf("Install one with: docker run --privileged --rm tonistiigi/binfmt --install %s", platformArchitecture))
+}
+
+// getHostArchitecture returns the lambda architecture of the host
+func getHostArchitecture() (string, error) {
+	return NormalizeArchitecture(runtime.GOARCH)
+}
+
+// checkImagePlatform returns an error if image is not available for platform.
+// Only images for a foreign architecture are checked, and images whose
+// platforms cannot be determined - such as single-platform images or images
+// that only exist locally - are assumed to be available
+func checkImagePlatform(ctx context.Context, engine ContainerEngine, image string, platform string) error {
+	if platform == "" {
+		return nil
+	}
+
+	hostArchitecture, err := getHostArchitecture()
+	if err == nil && (Config{Architecture: hostArchitecture}).GetPlatform() == platform {
+		return nil
+	}
+
+	if inspect, err := engine.InspectImage(ctx, image); err == nil && fmt.Sprintf("%s/%s", inspect.Os, inspect.Architecture) == platform {
+		return nil
+	}
+
+	platforms, err := engine.ImagePlatforms(ctx, image)
+	if err != nil || len(platforms) == 0 {
+		return nil
+	}
+
+	for _, p := range platforms {
+		if p == platform {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("image %s is not published for %s (available: %s), specify an image built for %s", image, platform, strings.Join(platforms, ", "), platform)
+}
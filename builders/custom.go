@@ -0,0 +1,276 @@
+package builders
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// validBuilderName matches the names allowed for user-defined builders
+var validBuilderName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// BuilderDefinition describes a user-defined builder, declared under the
+// builders key of a lambda.yml file or in a builder definition file
+type BuilderDefinition struct {
+	// BuildImage is the default build image, defaulting to the provided.al2 build image
+	BuildImage string `yaml:"build_image" toml:"build_image"`
+
+	// DependencyFiles are the files copied into the cached dependency layer
+	DependencyFiles []string `yaml:"dependency_files" toml:"dependency_files"`
+
+	// Detect is a list of globs, any of which matching a file in the working
+	// directory detects the builder
+	Detect []string `yaml:"detect" toml:"detect"`
+
+	// HandlerMap maps files to the handler used when they exist in the artifact
+	HandlerMap map[string]string `yaml:"handler_map" toml:"handler_map"`
+
+	// Name is the name of the builder
+	Name string `yaml:"name" toml:"name"`
+
+	// RunImage is the default run image, defaulting to the provided.al2 image
+	RunImage string `yaml:"run_image" toml:"run_image"`
+
+	// Script is the build script run within the build image
+	Script string `yaml:"script" toml:"script"`
+
+	// ScriptFile is a path to the build script, relative to the file
+	// declaring the builder, and is used when Script is empty
+	ScriptFile string `yaml:"script_file" toml:"script_file"`
+
+	// SlimPatterns are the paths removed from the artifacts when slimming
+	SlimPatterns []string `yaml:"slim_patterns" toml:"slim_patterns"`
+}
+
+// CustomBuilder is a builder defined by a BuilderDefinition
+type CustomBuilder struct {
+	Config     Config
+	Definition BuilderDefinition
+}
+
+func NewCustomBuilder(config Config, definition BuilderDefinition) (CustomBuilder, error) {
+	var err error
+	buildImage := definition.BuildImage
+	if buildImage == "" {
+		buildImage = "mlupin/docker-lambda:provided.al2-build"
+	}
+
+	runImage := definition.RunImage
+	if runImage == "" {
+		runImage = "mlupin/docker-lambda:provided.al2"
+	}
+
+	config.BuilderBuildImage, err = getBuildImage(config, buildImage)
+	if err != nil {
+		return CustomBuilder{}, err
+	}
+
+	config.BuilderRunImage, err = getRunImage(config, runImage)
+	if err != nil {
+		return CustomBuilder{}, err
+	}
+
+	return CustomBuilder{
+		Config:     config,
+		Definition: definition,
+	}, nil
+}
+
+func (b CustomBuilder) Detect() bool {
+	for _, pattern := range b.Definition.Detect {
+		matches, err := filepath.Glob(filepath.Join(b.Config.WorkingDirectory, pattern))
+		if err == nil && len(matches) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b CustomBuilder) Execute() error {
+	b.Config.Builder = b.Name()
+	b.Config.DependencyFiles = b.GetDependencyFiles()
+	b.Config.HandlerMap = b.GetHandlerMap()
+	b.Config.SlimPatterns = b.GetSlimPatterns()
+	return executeBuilder(b.script(), b.Config)
+}
+
+func (b CustomBuilder) GetBuildImage() string {
+	return b.Config.BuilderBuildImage
+}
+
+func (b CustomBuilder) GetConfig() Config {
+	return b.Config
+}
+
+func (b CustomBuilder) GetDependencyFiles() []string {
+	return append([]string{}, b.Definition.DependencyFiles...)
+}
+
+func (b CustomBuilder) GetHandlerMap() map[string]string {
+	handlerMap := map[string]string{}
+	for file, handler := range b.Definition.HandlerMap {
+		handlerMap[file] = handler
+	}
+
+	return handlerMap
+}
+
+func (b CustomBuilder) GetSlimPatterns() []string {
+	return append([]string{}, b.Definition.SlimPatterns...)
+}
+
+func (b CustomBuilder) Name() string {
+	return b.Definition.Name
+}
+
+func (b CustomBuilder) Shell() error {
+	return executeShell(b.script(), b.Config)
+}
+
+func (b CustomBuilder) script() string {
+	return b.Definition.Script
+}
+
+// BuilderDefinitionsDirectory returns the directory builder definition files
+// are loaded from, set via LAMBDA_BUILDER_BUILDERS_DIR and defaulting to
+// lambda-builder/builders within the user config directory
+func BuilderDefinitionsDirectory() string {
+	if directory := os.Getenv("LAMBDA_BUILDER_BUILDERS_DIR"); directory != "" {
+		return directory
+	}
+
+	configDirectory, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(configDirectory, "lambda-builder", "builders")
+}
+
+// LoadBuilderDefinitions registers the builders declared in the yaml and toml
+// files within directory, in lexical order. A missing directory is ignored, and
+// invalid files are skipped so that they do not prevent other builders or
+// commands from being used, with an error returned for each skipped file
+func LoadBuilderDefinitions(directory string) []error {
+	if directory == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(directory)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return []error{fmt.Errorf("error reading builder definitions: %w", err)}
+	}
+
+	files := []string{}
+	for _, entry := range entries {
+		switch filepath.Ext(entry.Name()) {
+		case ".toml", ".yaml", ".yml":
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(directory, entry.Name()))
+			}
+		}
+	}
+
+	sort.Strings(files)
+	errs := []error{}
+	for _, file := range files {
+		definition, err := parseBuilderDefinition(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := registerBuilder(definition.Name, definition.factory(), true); err != nil {
+			errs = append(errs, fmt.Errorf("error loading builder from %s: %w", file, err))
+		}
+	}
+
+	return errs
+}
+
+// parseBuilderDefinition reads and validates the builder declared in file
+func parseBuilderDefinition(file string) (BuilderDefinition, error) {
+	var definition BuilderDefinition
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		return definition, fmt.Errorf("error reading %s: %w", file, err)
+	}
+
+	if filepath.Ext(file) == ".toml" {
+		err = toml.Unmarshal(bytes, &definition)
+	} else {
+		err = yaml.Unmarshal(bytes, &definition)
+	}
+	if err != nil {
+		return definition, fmt.Errorf("error unmarshaling %s: %w", file, err)
+	}
+
+	if err := definition.load(filepath.Dir(file)); err != nil {
+		return definition, fmt.Errorf("error loading builder from %s: %w", file, err)
+	}
+
+	return definition, nil
+}
+
+// load reads the script file of the definition, relative to directory,
+// and validates the definition
+func (d *BuilderDefinition) load(directory string) error {
+	if d.Script == "" && d.ScriptFile != "" {
+		scriptFile := d.ScriptFile
+		if !filepath.IsAbs(scriptFile) {
+			scriptFile = filepath.Join(directory, scriptFile)
+		}
+
+		script, err := os.ReadFile(scriptFile)
+		if err != nil {
+			return fmt.Errorf("error reading script for builder %s: %w", d.Name, err)
+		}
+		d.Script = string(script)
+	}
+
+	return d.validate()
+}
+
+// validate returns an error if the definition cannot be used to build an app
+func (d BuilderDefinition) validate() error {
+	if !validBuilderName.MatchString(d.Name) {
+		return fmt.Errorf("invalid builder name '%s'", d.Name)
+	}
+
+	if len(d.Detect) == 0 {
+		return fmt.Errorf("builder %s does not specify any detect globs", d.Name)
+	}
+
+	for _, pattern := range d.Detect {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("builder %s has an invalid detect glob '%s': %w", d.Name, pattern, err)
+		}
+	}
+
+	if d.Script == "" {
+		return fmt.Errorf("builder %s does not specify a script", d.Name)
+	}
+
+	if !strings.HasPrefix(strings.TrimSpace(d.Script), "#!") {
+		return fmt.Errorf("script for builder %s must start with a shebang", d.Name)
+	}
+
+	return nil
+}
+
+// factory returns a BuilderFactory constructing the defined builder
+func (d BuilderDefinition) factory() BuilderFactory {
+	return func(config Config) (Builder, error) {
+		return NewCustomBuilder(config, d)
+	}
+}
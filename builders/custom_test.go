@@ -0,0 +1,40 @@
+package builders
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadBuilderDefinitionsSkipsInvalidFiles(t *testing.T) {
+	directory := t.TempDir()
+	files := map[string]string{
+		"a-invalid.yml":    "name: test-invalid\ndetect: [\"*.x\"]\nscript: \"no shebang\"\n",
+		"b-malformed.toml": "name = \n",
+		"c-valid.yml":      "name: test-valid\ndetect: [\"*.x\"]\nscript: \"#!/usr/bin/env bash\\n\"\n",
+	}
+
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(directory, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	errs := LoadBuilderDefinitions(directory)
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 skipped definitions, got %v", errs)
+	}
+
+	registered := map[string]bool{}
+	for _, name := range BuilderNames() {
+		registered[name] = true
+	}
+
+	if !registered["test-valid"] {
+		t.Error("expected the valid builder to be registered")
+	}
+
+	if registered["test-invalid"] {
+		t.Error("expected the invalid builder not to be registered")
+	}
+}
@@ -1,6 +1,10 @@
 package builders
 
-import "lambda-builder/io"
+import (
+	"fmt"
+
+	"lambda-builder/io"
+)
 
 type DotnetBuilder struct {
 	Config Config
@@ -32,7 +36,14 @@ func (b DotnetBuilder) Detect() bool {
 }
 
 func (b DotnetBuilder) Execute() error {
+	if b.Config.Layer {
+		return fmt.Errorf("the %s builder does not support layer builds", b.Name())
+	}
+
+	b.Config.Builder = b.Name()
+	b.Config.DependencyFiles = b.GetDependencyFiles()
 	b.Config.HandlerMap = b.GetHandlerMap()
+	b.Config.SlimPatterns = b.GetSlimPatterns()
 	return executeBuilder(b.script(), b.Config)
 }
 
@@ -44,14 +55,30 @@ func (b DotnetBuilder) GetConfig() Config {
 	return b.Config
 }
 
+func (b DotnetBuilder) GetDependencyFiles() []string {
+	return []string{
+		"*.csproj",
+		"*.fsproj",
+		"*.sln",
+	}
+}
+
 func (b DotnetBuilder) GetHandlerMap() map[string]string {
 	return map[string]string{}
 }
 
+func (b DotnetBuilder) GetSlimPatterns() []string {
+	return []string{}
+}
+
 func (b DotnetBuilder) Name() string {
 	return "dotnet"
 }
 
+func (b DotnetBuilder) Shell() error {
+	return executeShell(b.script(), b.Config)
+}
+
 func (b DotnetBuilder) script() string {
 	return `
 #!/usr/bin/env bash
@@ -101,6 +128,12 @@ hook-package() {
   zip -q -r lambda.zip ./*
 }
 
+if [[ "$LAMBDA_BUILD_PHASE" == "dependencies" ]]; then
+  puts-step "Restoring dependencies via dotnet restore"
+  dotnet restore 2>&1 | indent
+  exit 0
+fi
+
 hook-pre-compile
 install-dotnet
 hook-post-compile
@@ -0,0 +1,231 @@
+package builders
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+
+	"lambda-builder/ui"
+)
+
+// ContainerEngine wraps the container runtime used to build images
+// and extract artifacts from them
+type ContainerEngine interface {
+	// BuildImage builds an image from a Dockerfile and build context
+	BuildImage(ctx context.Context, input BuildImageInput) error
+
+	// CopyFromImage copies a single file out of an image onto the host
+	CopyFromImage(ctx context.Context, input CopyFromImageInput) error
+
+	// ImagePlatforms returns the `os/arch` platforms an image is published for
+	// in its registry, or an empty list if they cannot be determined
+	ImagePlatforms(ctx context.Context, image string) ([]string, error)
+
+	// InspectImage returns the identifiers of a local image
+	InspectImage(ctx context.Context, image string) (ImageInspect, error)
+
+	// Name returns the name of the engine
+	Name() string
+
+	// RemoveContainer force-removes a container by name
+	RemoveContainer(ctx context.Context, name string) error
+
+	// RemoveImage force-removes an image by tag
+	RemoveImage(ctx context.Context, image string) error
+
+	// RunContainer runs a container attached to the current terminal
+	RunContainer(ctx context.Context, input RunContainerInput) error
+
+	// StartContainer starts a container in the background
+	StartContainer(ctx context.Context, input StartContainerInput) error
+}
+
+// ImageInspect contains the identifiers of an image
+type ImageInspect struct {
+	// Architecture is the cpu architecture the image is built for
+	Architecture string `json:"Architecture"`
+
+	// Config is the default configuration of containers run from the image
+	Config ImageConfig `json:"Config"`
+
+	// ID is the content-addressable ID of the image
+	ID string `json:"Id"`
+
+	// Os is the operating system the image is built for
+	Os string `json:"Os"`
+
+	// RepoDigests lists the repository digests the image is known by
+	RepoDigests []string `json:"RepoDigests"`
+}
+
+// ImageConfig contains the default configuration of containers run from an image
+type ImageConfig struct {
+	// Cmd is the default command of the image
+	Cmd []string `json:"Cmd"`
+
+	// Env is the list of `KEY=VALUE` environment variables set by the image
+	Env []string `json:"Env"`
+}
+
+// BuildImageInput contains the options used when building an image
+type BuildImageInput struct {
+	// BuildContext is the directory sent as the build context
+	BuildContext string
+
+	// DockerfilePath is the path to the Dockerfile on the host
+	DockerfilePath string
+
+	// Labels is a list of `key=value` labels to set on the image
+	Labels []string
+
+	// Output receives the streamed build output, which is discarded when nil
+	Output io.Writer
+
+	// Platform is the target platform of the image, in `os/arch` format
+	Platform string
+
+	// Tag is the name and optionally a tag in the 'name:tag' format
+	Tag string
+}
+
+// CopyFromImageInput contains the options used when copying a file out of an image
+type CopyFromImageInput struct {
+	// ContainerName is the name to use for the temporary container
+	ContainerName string
+
+	// Destination is the path on the host to write the file to
+	Destination string
+
+	// Image is the image to copy the file from
+	Image string
+
+	// Labels is a list of `key=value` labels to set on the temporary container
+	Labels []string
+
+	// Output receives the streamed container output, which is discarded when nil
+	Output io.Writer
+
+	// Platform is the platform of the image, in `os/arch` format
+	Platform string
+
+	// Source is the absolute path to the file within the image
+	Source string
+}
+
+// RunContainerInput contains the options used when running a container
+type RunContainerInput struct {
+	// Command is the command to run within the container
+	Command []string
+
+	// ContainerName is the name to use for the container
+	ContainerName string
+
+	// Env is a list of `KEY=VALUE` environment variables to set
+	Env []string
+
+	// Image is the image to run
+	Image string
+
+	// Labels is a list of `key=value` labels to set on the container
+	Labels []string
+
+	// Platform is the platform of the image, in `os/arch` format
+	Platform string
+
+	// Volumes is a list of `host-path:container-path[:options]` bind mounts
+	Volumes []string
+
+	// WorkingDirectory is the working directory within the container
+	WorkingDirectory string
+}
+
+// StartContainerInput contains the options used when starting a container in the background
+type StartContainerInput struct {
+	// Command overrides the default command of the image, if set
+	Command []string
+
+	// ContainerName is the name to use for the container
+	ContainerName string
+
+	// Env is a list of `KEY=VALUE` environment variables to set
+	Env []string
+
+	// Image is the image to run
+	Image string
+
+	// Labels is a list of `key=value` labels to set on the container
+	Labels []string
+
+	// Platform is the platform of the image, in `os/arch` format
+	Platform string
+
+	// Ports is a list of `host-ip:host-port:container-port` ports to publish
+	Ports []string
+}
+
+// ContainerEngines is the list of selectable container engines
+var ContainerEngines = []string{"docker", "docker-api", "nerdctl", "podman"}
+
+// NewContainerEngine returns the container engine for the given name
+func NewContainerEngine(name string) (ContainerEngine, error) {
+	switch name {
+	case "", "docker":
+		return NewDockerEngine(), nil
+	case "docker-api":
+		engine, err := NewDockerApiEngine()
+		if err != nil {
+			return nil, err
+		}
+		return engine, nil
+	case "nerdctl":
+		return NewNerdctlEngine(), nil
+	case "podman":
+		return NewPodmanEngine(), nil
+	}
+
+	return nil, fmt.Errorf("unsupported container engine: %s", name)
+}
+
+func getContainerEngine(config Config) (ContainerEngine, error) {
+	if config.ContainerEngine != nil {
+		return config.ContainerEngine, nil
+	}
+
+	if config.Engine != "" {
+		return NewContainerEngine(config.Engine)
+	}
+
+	lambdaYML, err := ParseLambdaYML(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewContainerEngine(lambdaYML.Engine)
+}
+
+// progressPrefix matches the step number and timestamp buildkit
+// prefixes each line of output with in plain progress mode
+var progressPrefix = regexp.MustCompile(`^#\d+ \d+(\.\d+)? `)
+
+// newOutputWriter returns the writer that container output for a build phase
+// is logged through along with a function that flushes it, or a nil writer
+// when the build is run quietly
+func newOutputWriter(config Config, phase string) (io.Writer, func()) {
+	if config.RunQuiet {
+		return nil, func() {}
+	}
+
+	w := ui.NewLineWriter(config.GetLogger().Field("phase", phase))
+	w.Transform = func(line string) string {
+		return progressPrefix.ReplaceAllString(line, "")
+	}
+
+	return w, w.Flush
+}
+
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
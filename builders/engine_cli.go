@@ -0,0 +1,301 @@
+package builders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/mattn/go-isatty"
+)
+
+// CliEngine drives a docker-compatible container cli
+type CliEngine struct {
+	// Binary is the name or path of the cli binary
+	Binary string
+
+	// BuildFlags are extra flags passed when building an image
+	BuildFlags []string
+}
+
+// NewDockerEngine returns an engine that shells out to the docker cli
+func NewDockerEngine() CliEngine {
+	return CliEngine{
+		Binary:     "docker",
+		BuildFlags: []string{"--progress", "plain"},
+	}
+}
+
+// NewNerdctlEngine returns an engine that shells out to the nerdctl cli
+func NewNerdctlEngine() CliEngine {
+	return CliEngine{
+		Binary:     "nerdctl",
+		BuildFlags: []string{"--progress", "plain"},
+	}
+}
+
+// NewPodmanEngine returns an engine that shells out to the podman cli
+func NewPodmanEngine() CliEngine {
+	return CliEngine{
+		Binary: "podman",
+	}
+}
+
+func (e CliEngine) Name() string {
+	return e.Binary
+}
+
+func (e CliEngine) BuildImage(ctx context.Context, input BuildImageInput) error {
+	args := []string{
+		"image",
+		"build",
+		"--file", input.DockerfilePath,
+		"--tag", input.Tag,
+	}
+	args = append(args, e.BuildFlags...)
+
+	if input.Platform != "" {
+		args = append(args, "--platform", input.Platform)
+	}
+
+	for _, label := range input.Labels {
+		args = append(args, "--label", label)
+	}
+
+	args = append(args, input.BuildContext)
+
+	if err := e.execute(ctx, args, input.Output); err != nil {
+		return fmt.Errorf("error building image: %w", err)
+	}
+
+	return nil
+}
+
+// CopyFromImage creates a stopped container from the image and copies the
+// file out of it, avoiding bind mounts so that remote daemons are supported
+func (e CliEngine) CopyFromImage(ctx context.Context, input CopyFromImageInput) error {
+	args := []string{
+		"container",
+		"create",
+		"--name", input.ContainerName,
+	}
+
+	if input.Platform != "" {
+		args = append(args, "--platform", input.Platform)
+	}
+
+	for _, label := range input.Labels {
+		args = append(args, "--label", label)
+	}
+
+	args = append(args, input.Image, "/bin/true")
+
+	if err := e.execute(ctx, args, nil); err != nil {
+		return fmt.Errorf("error creating container: %w", err)
+	}
+
+	defer func() {
+		e.execute(context.Background(), []string{"container", "rm", "--force", input.ContainerName}, nil)
+	}()
+
+	args = []string{
+		"container",
+		"cp",
+		fmt.Sprintf("%s:%s", input.ContainerName, input.Source),
+		input.Destination,
+	}
+
+	if err := e.execute(ctx, args, input.Output); err != nil {
+		return fmt.Errorf("error copying %s from image: %w", input.Source, err)
+	}
+
+	return nil
+}
+
+func (e CliEngine) ImagePlatforms(ctx context.Context, image string) ([]string, error) {
+	stdout, err := exec.CommandContext(ctx, e.Binary, "manifest", "inspect", image).Output()
+	if err != nil {
+		return nil, fmt.Errorf("error inspecting manifest: %w", err)
+	}
+
+	// only manifest lists describe the platforms of an image
+	var manifestList struct {
+		Manifests []struct {
+			Platform struct {
+				Architecture string `json:"architecture"`
+				OS           string `json:"os"`
+			} `json:"platform"`
+		} `json:"manifests"`
+	}
+	if err := json.Unmarshal(stdout, &manifestList); err != nil {
+		return nil, fmt.Errorf("error decoding manifest inspect output: %w", err)
+	}
+
+	platforms := []string{}
+	for _, manifest := range manifestList.Manifests {
+		platforms = append(platforms, fmt.Sprintf("%s/%s", manifest.Platform.OS, manifest.Platform.Architecture))
+	}
+
+	return platforms, nil
+}
+
+func (e CliEngine) InspectImage(ctx context.Context, image string) (ImageInspect, error) {
+	stdout, err := exec.CommandContext(ctx, e.Binary, "image", "inspect", image).Output()
+	if err != nil {
+		return ImageInspect{}, fmt.Errorf("error inspecting image: %w", err)
+	}
+
+	var inspects []ImageInspect
+	if err := json.Unmarshal(stdout, &inspects); err != nil {
+		return ImageInspect{}, fmt.Errorf("error decoding image inspect output: %w", err)
+	}
+
+	if len(inspects) == 0 {
+		return ImageInspect{}, fmt.Errorf("image %s not found", image)
+	}
+
+	return inspects[0], nil
+}
+
+func (e CliEngine) RemoveContainer(ctx context.Context, name string) error {
+	args := []string{
+		"container",
+		"rm",
+		"--force",
+		name,
+	}
+
+	return e.execute(ctx, args, nil)
+}
+
+func (e CliEngine) RemoveImage(ctx context.Context, image string) error {
+	args := []string{
+		"image",
+		"rm",
+		"--force",
+		image,
+	}
+
+	return e.execute(ctx, args, nil)
+}
+
+// RunContainer runs a container with stdio attached to the current process,
+// allocating a tty when stdin is a terminal
+func (e CliEngine) RunContainer(ctx context.Context, input RunContainerInput) error {
+	args := []string{
+		"container",
+		"run",
+		"--rm",
+		"--interactive",
+		"--name", input.ContainerName,
+	}
+
+	if isatty.IsTerminal(os.Stdin.Fd()) {
+		args = append(args, "--tty")
+	}
+
+	if input.Platform != "" {
+		args = append(args, "--platform", input.Platform)
+	}
+
+	for _, env := range input.Env {
+		args = append(args, "--env", env)
+	}
+
+	for _, label := range input.Labels {
+		args = append(args, "--label", label)
+	}
+
+	for _, volume := range input.Volumes {
+		args = append(args, "--volume", volume)
+	}
+
+	if input.WorkingDirectory != "" {
+		args = append(args, "--workdir", input.WorkingDirectory)
+	}
+
+	args = append(args, input.Image)
+	args = append(args, input.Command...)
+
+	cmd := exec.CommandContext(ctx, e.Binary, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return &ExitError{
+				Command:  e.Binary,
+				ExitCode: exitErr.ExitCode(),
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (e CliEngine) StartContainer(ctx context.Context, input StartContainerInput) error {
+	args := []string{
+		"container",
+		"run",
+		"--detach",
+		"--name", input.ContainerName,
+	}
+
+	if input.Platform != "" {
+		args = append(args, "--platform", input.Platform)
+	}
+
+	for _, env := range input.Env {
+		args = append(args, "--env", env)
+	}
+
+	for _, label := range input.Labels {
+		args = append(args, "--label", label)
+	}
+
+	for _, port := range input.Ports {
+		args = append(args, "--publish", port)
+	}
+
+	args = append(args, input.Image)
+	args = append(args, input.Command...)
+
+	if err := e.execute(ctx, args, nil); err != nil {
+		return fmt.Errorf("error starting container: %w", err)
+	}
+
+	return nil
+}
+
+// execute runs the cli, streaming its combined output to the output writer
+// if one is given. The tail of the output is always retained so that it
+// can be included in the returned error should the command fail
+func (e CliEngine) execute(ctx context.Context, args []string, output io.Writer) error {
+	captured := newTailWriter(outputTailLines)
+	writer := io.Writer(captured)
+	if output != nil {
+		writer = io.MultiWriter(captured, output)
+	}
+
+	cmd := exec.CommandContext(ctx, e.Binary, args...)
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return &ExitError{
+				Command:  e.Binary,
+				ExitCode: exitErr.ExitCode(),
+				Output:   captured.Lines(),
+			}
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,539 @@
+package builders
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	defaultDockerHost = "unix:///var/run/docker.sock"
+
+	// apiDockerfileName is the name of the Dockerfile within the uploaded build context
+	apiDockerfileName = ".lambda-builder.Dockerfile"
+)
+
+// DockerApiEngine talks to the Docker Engine API directly over a socket
+type DockerApiEngine struct {
+	// Host is the address of the docker daemon, in DOCKER_HOST format
+	Host string
+
+	client *http.Client
+}
+
+// DockerApiError is returned when the Docker Engine API responds with an error status
+type DockerApiError struct {
+	// Message is the error message returned by the daemon
+	Message string
+
+	// StatusCode is the http status code of the response
+	StatusCode int
+}
+
+func (e *DockerApiError) Error() string {
+	return fmt.Sprintf("docker api error (status %d): %s", e.StatusCode, e.Message)
+}
+
+// DockerBuildError is returned when a Dockerfile step fails during an image build
+type DockerBuildError struct {
+	// Code is the exit code of the failing step, if any
+	Code int
+
+	// Message is the error message returned by the daemon
+	Message string
+
+	// Output contains the output streamed by the failing step
+	Output []string
+
+	// Step is the Dockerfile step that failed
+	Step string
+}
+
+func (e *DockerBuildError) Error() string {
+	if e.Step == "" {
+		return e.Message
+	}
+
+	return fmt.Sprintf("%s: %s", e.Step, e.Message)
+}
+
+type dockerBuildMessage struct {
+	Error       string `json:"error"`
+	ErrorDetail struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+	Status string `json:"status"`
+	Stream string `json:"stream"`
+}
+
+// NewDockerApiEngine returns an engine for the daemon referenced by DOCKER_HOST
+func NewDockerApiEngine() (*DockerApiEngine, error) {
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		host = defaultDockerHost
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing docker host %s: %w", host, err)
+	}
+
+	var dial func(ctx context.Context, network, addr string) (net.Conn, error)
+	switch u.Scheme {
+	case "unix":
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", u.Path)
+		}
+	case "tcp":
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "tcp", u.Host)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported docker host scheme: %s", u.Scheme)
+	}
+
+	return &DockerApiEngine{
+		Host: host,
+		client: &http.Client{
+			Transport: &http.Transport{DialContext: dial},
+		},
+	}, nil
+}
+
+func (e *DockerApiEngine) Name() string {
+	return "docker-api"
+}
+
+func (e *DockerApiEngine) BuildImage(ctx context.Context, input BuildImageInput) error {
+	dockerfile, err := os.ReadFile(input.DockerfilePath)
+	if err != nil {
+		return fmt.Errorf("error reading Dockerfile: %w", err)
+	}
+
+	encodedLabels, err := json.Marshal(parseLabels(input.Labels))
+	if err != nil {
+		return fmt.Errorf("error encoding labels: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("dockerfile", apiDockerfileName)
+	query.Set("forcerm", "1")
+	query.Set("labels", string(encodedLabels))
+	query.Set("t", input.Tag)
+	if input.Platform != "" {
+		query.Set("platform", input.Platform)
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeBuildContext(writer, input.BuildContext, dockerfile))
+	}()
+	defer reader.Close()
+
+	res, err := e.request(ctx, http.MethodPost, "/build", query, "application/x-tar", reader)
+	if err != nil {
+		return fmt.Errorf("error building image: %w", err)
+	}
+	defer res.Body.Close()
+
+	step := ""
+	output := []string{}
+	decoder := json.NewDecoder(res.Body)
+	for {
+		var message dockerBuildMessage
+		if err := decoder.Decode(&message); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("error reading build output: %w", err)
+		}
+
+		if message.Error != "" {
+			buildErr := &DockerBuildError{
+				Code:    message.ErrorDetail.Code,
+				Message: message.Error,
+				Output:  output,
+				Step:    step,
+			}
+			return fmt.Errorf("error building image: %w", buildErr)
+		}
+
+		if message.Stream == "" {
+			continue
+		}
+
+		if input.Output != nil {
+			io.WriteString(input.Output, message.Stream)
+		}
+
+		for _, line := range strings.Split(strings.TrimRight(message.Stream, "\n"), "\n") {
+			if strings.HasPrefix(line, "Step ") {
+				step = line
+				output = []string{}
+				continue
+			}
+			output = append(output, line)
+		}
+	}
+
+	return nil
+}
+
+func (e *DockerApiEngine) CopyFromImage(ctx context.Context, input CopyFromImageInput) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"Cmd":    []string{"/bin/true"},
+		"Image":  input.Image,
+		"Labels": parseLabels(input.Labels),
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding container config: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("name", input.ContainerName)
+	if input.Platform != "" {
+		query.Set("platform", input.Platform)
+	}
+	res, err := e.request(ctx, http.MethodPost, "/containers/create", query, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating container: %w", err)
+	}
+
+	var created struct {
+		Id string `json:"Id"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&created)
+	res.Body.Close()
+	if err != nil {
+		return fmt.Errorf("error reading created container: %w", err)
+	}
+
+	defer func() {
+		query := url.Values{}
+		query.Set("force", "1")
+		if res, err := e.request(context.Background(), http.MethodDelete, "/containers/"+created.Id, query, "", nil); err == nil {
+			res.Body.Close()
+		}
+	}()
+
+	query = url.Values{}
+	query.Set("path", input.Source)
+	res, err = e.request(ctx, http.MethodGet, fmt.Sprintf("/containers/%s/archive", created.Id), query, "", nil)
+	if err != nil {
+		return fmt.Errorf("error copying %s from container: %w", input.Source, err)
+	}
+	defer res.Body.Close()
+
+	tr := tar.NewReader(res.Body)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error reading archive for %s: %w", input.Source, err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		return writeFileAtomically(input.Destination, tr, 0644)
+	}
+
+	return fmt.Errorf("file %s not found in image %s", input.Source, input.Image)
+}
+
+func (e *DockerApiEngine) ImagePlatforms(ctx context.Context, image string) ([]string, error) {
+	res, err := e.request(ctx, http.MethodGet, "/distribution/"+image+"/json", nil, "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error inspecting distribution: %w", err)
+	}
+	defer res.Body.Close()
+
+	var distribution struct {
+		Platforms []struct {
+			Architecture string `json:"architecture"`
+			OS           string `json:"os"`
+		} `json:"Platforms"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&distribution); err != nil {
+		return nil, fmt.Errorf("error decoding distribution response: %w", err)
+	}
+
+	platforms := []string{}
+	for _, platform := range distribution.Platforms {
+		platforms = append(platforms, fmt.Sprintf("%s/%s", platform.OS, platform.Architecture))
+	}
+
+	return platforms, nil
+}
+
+func (e *DockerApiEngine) InspectImage(ctx context.Context, image string) (ImageInspect, error) {
+	var inspect ImageInspect
+	res, err := e.request(ctx, http.MethodGet, "/images/"+image+"/json", nil, "", nil)
+	if err != nil {
+		return inspect, fmt.Errorf("error inspecting image: %w", err)
+	}
+	defer res.Body.Close()
+
+	if err := json.NewDecoder(res.Body).Decode(&inspect); err != nil {
+		return inspect, fmt.Errorf("error decoding image inspect response: %w", err)
+	}
+
+	return inspect, nil
+}
+
+func (e *DockerApiEngine) RemoveContainer(ctx context.Context, name string) error {
+	query := url.Values{}
+	query.Set("force", "1")
+	res, err := e.request(ctx, http.MethodDelete, "/containers/"+name, query, "", nil)
+	if err != nil {
+		return fmt.Errorf("error removing container: %w", err)
+	}
+
+	return res.Body.Close()
+}
+
+func (e *DockerApiEngine) RemoveImage(ctx context.Context, image string) error {
+	query := url.Values{}
+	query.Set("force", "1")
+	res, err := e.request(ctx, http.MethodDelete, "/images/"+image, query, "", nil)
+	if err != nil {
+		return fmt.Errorf("error removing image: %w", err)
+	}
+
+	return res.Body.Close()
+}
+
+// RunContainer is not supported as attaching a terminal requires hijacking
+// the connection to the daemon
+func (e *DockerApiEngine) RunContainer(ctx context.Context, input RunContainerInput) error {
+	return errors.New("running interactive containers is not supported by the docker-api engine")
+}
+
+func (e *DockerApiEngine) StartContainer(ctx context.Context, input StartContainerInput) error {
+	exposedPorts := map[string]struct{}{}
+	portBindings := map[string][]map[string]string{}
+	for _, port := range input.Ports {
+		parts := strings.Split(port, ":")
+		containerPort := fmt.Sprintf("%s/tcp", parts[len(parts)-1])
+		binding := map[string]string{}
+		if len(parts) > 1 {
+			binding["HostPort"] = parts[len(parts)-2]
+		}
+		if len(parts) > 2 {
+			binding["HostIp"] = strings.Join(parts[:len(parts)-2], ":")
+		}
+
+		exposedPorts[containerPort] = struct{}{}
+		portBindings[containerPort] = append(portBindings[containerPort], binding)
+	}
+
+	config := map[string]interface{}{
+		"Env":          input.Env,
+		"ExposedPorts": exposedPorts,
+		"HostConfig": map[string]interface{}{
+			"PortBindings": portBindings,
+		},
+		"Image":  input.Image,
+		"Labels": parseLabels(input.Labels),
+	}
+	if len(input.Command) > 0 {
+		config["Cmd"] = input.Command
+	}
+
+	body, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("error encoding container config: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("name", input.ContainerName)
+	if input.Platform != "" {
+		query.Set("platform", input.Platform)
+	}
+	res, err := e.request(ctx, http.MethodPost, "/containers/create", query, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating container: %w", err)
+	}
+	res.Body.Close()
+
+	res, err = e.request(ctx, http.MethodPost, fmt.Sprintf("/containers/%s/start", input.ContainerName), nil, "", nil)
+	if err != nil {
+		return fmt.Errorf("error starting container: %w", err)
+	}
+
+	return res.Body.Close()
+}
+
+func (e *DockerApiEngine) request(ctx context.Context, method string, path string, query url.Values, contentType string, body io.Reader) (*http.Response, error) {
+	u := url.URL{
+		Scheme:   "http",
+		Host:     "docker",
+		Path:     path,
+		RawQuery: query.Encode(),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode >= 400 {
+		defer res.Body.Close()
+		var message struct {
+			Message string `json:"message"`
+		}
+		data, _ := io.ReadAll(res.Body)
+		if err := json.Unmarshal(data, &message); err != nil || message.Message == "" {
+			message.Message = strings.TrimSpace(string(data))
+		}
+
+		return nil, &DockerApiError{
+			Message:    message.Message,
+			StatusCode: res.StatusCode,
+		}
+	}
+
+	return res, nil
+}
+
+// parseLabels converts a list of `key=value` labels into a map
+func parseLabels(labels []string) map[string]string {
+	parsed := map[string]string{}
+	for _, label := range labels {
+		parts := strings.SplitN(label, "=", 2)
+		if len(parts) == 1 {
+			parts = append(parts, "")
+		}
+		parsed[parts[0]] = parts[1]
+	}
+
+	return parsed
+}
+
+// writeBuildContext writes the directory and Dockerfile as a tar stream. A
+// .dockerignore excluding the Dockerfile is added so the daemon removes it
+// from the context rather than copying it into the image.
+func writeBuildContext(w io.Writer, directory string, dockerfile []byte) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+
+		if name == "." || name == ".dockerignore" || name == apiDockerfileName {
+			return nil
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+
+		header.Name = filepath.ToSlash(name)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	extraFiles := map[string][]byte{
+		".dockerignore":   []byte(fmt.Sprintf(".dockerignore\n%s\n", apiDockerfileName)),
+		apiDockerfileName: dockerfile,
+	}
+	for _, name := range []string{".dockerignore", apiDockerfileName} {
+		header := &tar.Header{
+			Mode: 0644,
+			Name: name,
+			Size: int64(len(extraFiles[name])),
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(extraFiles[name]); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+// writeFileAtomically writes the contents of the reader to a temporary file
+// next to the destination and renames it into place
+func writeFileAtomically(destination string, r io.Reader, mode os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(destination), fmt.Sprintf(".%s-", filepath.Base(destination)))
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", destination, err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing %s: %w", destination, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error writing %s: %w", destination, err)
+	}
+
+	if err := os.Chmod(f.Name(), mode); err != nil {
+		return fmt.Errorf("error writing %s: %w", destination, err)
+	}
+
+	return os.Rename(f.Name(), destination)
+}
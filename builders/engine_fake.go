@@ -0,0 +1,86 @@
+package builders
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FakeEngine is a ContainerEngine that records calls instead of talking
+// to a container runtime, allowing the build pipeline to run without a daemon
+type FakeEngine struct {
+	// Calls records a description of each method invocation in order
+	Calls []string
+
+	// Err, when set, is returned from every call
+	Err error
+
+	// Files maps paths within an image to the contents written
+	// to the host when they are copied out
+	Files map[string][]byte
+
+	// Platforms maps images to the platforms they are published for
+	Platforms map[string][]string
+
+	mu sync.Mutex
+}
+
+func (e *FakeEngine) Name() string {
+	return "fake"
+}
+
+func (e *FakeEngine) BuildImage(ctx context.Context, input BuildImageInput) error {
+	e.record(fmt.Sprintf("build %s", input.Tag))
+	return e.Err
+}
+
+func (e *FakeEngine) CopyFromImage(ctx context.Context, input CopyFromImageInput) error {
+	e.record(fmt.Sprintf("copy %s:%s %s", input.Image, input.Source, input.Destination))
+	if e.Err != nil {
+		return e.Err
+	}
+
+	data, ok := e.Files[input.Source]
+	if !ok {
+		return fmt.Errorf("file %s not found in image %s", input.Source, input.Image)
+	}
+
+	return os.WriteFile(input.Destination, data, 0644)
+}
+
+func (e *FakeEngine) ImagePlatforms(ctx context.Context, image string) ([]string, error) {
+	e.record(fmt.Sprintf("platforms %s", image))
+	return e.Platforms[image], e.Err
+}
+
+func (e *FakeEngine) InspectImage(ctx context.Context, image string) (ImageInspect, error) {
+	e.record(fmt.Sprintf("inspect %s", image))
+	return ImageInspect{}, e.Err
+}
+
+func (e *FakeEngine) RemoveContainer(ctx context.Context, name string) error {
+	e.record(fmt.Sprintf("rm %s", name))
+	return e.Err
+}
+
+func (e *FakeEngine) RemoveImage(ctx context.Context, image string) error {
+	e.record(fmt.Sprintf("rmi %s", image))
+	return e.Err
+}
+
+func (e *FakeEngine) RunContainer(ctx context.Context, input RunContainerInput) error {
+	e.record(fmt.Sprintf("run %s", input.Image))
+	return e.Err
+}
+
+func (e *FakeEngine) StartContainer(ctx context.Context, input StartContainerInput) error {
+	e.record(fmt.Sprintf("start %s", input.Image))
+	return e.Err
+}
+
+func (e *FakeEngine) record(call string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.Calls = append(e.Calls, call)
+}
@@ -0,0 +1,157 @@
+package builders
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// PhaseBuild is the phase in which the build image is built
+	PhaseBuild = "build"
+
+	// PhaseExtract is the phase in which artifacts are copied out of the build image
+	PhaseExtract = "extract"
+
+	// PhaseImage is the phase in which the run image is built
+	PhaseImage = "image"
+
+	// PhasePackage is the phase in which the extracted artifact is processed
+	PhasePackage = "package"
+
+	// outputTailLines is the number of lines of output kept on a BuildError
+	outputTailLines = 20
+)
+
+var (
+	// ErrBuildFailed matches errors raised while building the build image
+	ErrBuildFailed = errors.New("build failed")
+
+	// ErrExtractFailed matches errors raised while extracting artifacts from the build image
+	ErrExtractFailed = errors.New("extract failed")
+
+	// ErrImageFailed matches errors raised while building the run image
+	ErrImageFailed = errors.New("image failed")
+
+	// ErrPackageFailed matches errors raised while processing the extracted artifact
+	ErrPackageFailed = errors.New("package failed")
+)
+
+// BuildError is returned when a phase of the build pipeline fails
+type BuildError struct {
+	// Err is the underlying error
+	Err error
+
+	// ExitCode is the exit code of the failing process, if any
+	ExitCode int
+
+	// Output contains the tail of the output of the failing process
+	Output []string
+
+	// Phase is the phase of the build pipeline that failed
+	Phase string
+}
+
+func (e *BuildError) Error() string {
+	return fmt.Sprintf("%s phase failed: %s", e.Phase, e.Err.Error())
+}
+
+func (e *BuildError) Unwrap() error {
+	return e.Err
+}
+
+// Is allows matching a BuildError against the sentinel error for its phase
+func (e *BuildError) Is(target error) bool {
+	switch e.Phase {
+	case PhaseBuild:
+		return target == ErrBuildFailed
+	case PhaseExtract:
+		return target == ErrExtractFailed
+	case PhaseImage:
+		return target == ErrImageFailed
+	case PhasePackage:
+		return target == ErrPackageFailed
+	}
+
+	return false
+}
+
+// ExitError is returned by a container engine when a command exits non-zero
+type ExitError struct {
+	// Command is the command that was executed
+	Command string
+
+	// ExitCode is the exit code of the command
+	ExitCode int
+
+	// Output contains the combined stdout and stderr of the command
+	Output []string
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("%s exited with code %d", e.Command, e.ExitCode)
+}
+
+func newBuildError(phase string, err error) *BuildError {
+	buildErr := &BuildError{
+		Err:   err,
+		Phase: phase,
+	}
+
+	var exitErr *ExitError
+	var dockerBuildErr *DockerBuildError
+	if errors.As(err, &exitErr) {
+		buildErr.ExitCode = exitErr.ExitCode
+		buildErr.Output = tailLines(exitErr.Output, outputTailLines)
+	} else if errors.As(err, &dockerBuildErr) {
+		buildErr.ExitCode = dockerBuildErr.Code
+		buildErr.Output = tailLines(dockerBuildErr.Output, outputTailLines)
+	}
+
+	return buildErr
+}
+
+// tailWriter is an io.Writer that retains the last lines written to it,
+// so the output of a failed command can be reported without keeping all of it
+type tailWriter struct {
+	buf   []byte
+	lines []string
+	max   int
+}
+
+func newTailWriter(max int) *tailWriter {
+	return &tailWriter{max: max}
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		w.lines = tailLines(append(w.lines, strings.TrimRight(string(w.buf[:i]), "\r")), w.max)
+		w.buf = w.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Lines returns the retained lines, including any trailing partial line
+func (w *tailWriter) Lines() []string {
+	if len(w.buf) == 0 {
+		return w.lines
+	}
+
+	return tailLines(append(w.lines, string(w.buf)), w.max)
+}
+
+func tailLines(lines []string, count int) []string {
+	if len(lines) <= count {
+		return lines
+	}
+
+	return lines[len(lines)-count:]
+}
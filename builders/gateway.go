@@ -0,0 +1,193 @@
+package builders
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+const (
+	// GatewayPayloadVersion1 is the REST API proxy integration payload format
+	GatewayPayloadVersion1 = "1.0"
+
+	// GatewayPayloadVersion2 is the HTTP API proxy integration payload format
+	GatewayPayloadVersion2 = "2.0"
+
+	// gatewayAccountID is the placeholder account id set in generated events
+	gatewayAccountID = "123456789012"
+
+	// gatewayAPIID is the placeholder api id set in generated events
+	gatewayAPIID = "local"
+)
+
+// GatewayPayloadVersions is the list of supported API Gateway payload formats
+var GatewayPayloadVersions = []string{GatewayPayloadVersion1, GatewayPayloadVersion2}
+
+// NewGatewayEvent translates an http request into an API Gateway proxy event
+// using the given payload format
+func NewGatewayEvent(r *http.Request, version string) ([]byte, error) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading request body: %w", err)
+	}
+
+	// binary bodies are base64 encoded, as API Gateway does for binary media types
+	body, isBase64Encoded := string(data), false
+	if !utf8.Valid(data) {
+		body, isBase64Encoded = base64.StdEncoding.EncodeToString(data), true
+	}
+
+	now := time.Now().UTC()
+	requestID := uuid.New().String()
+	sourceIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		sourceIP = r.RemoteAddr
+	}
+
+	switch version {
+	case GatewayPayloadVersion1:
+		event := events.APIGatewayProxyRequest{
+			Body:                            body,
+			HTTPMethod:                      r.Method,
+			Headers:                         map[string]string{},
+			IsBase64Encoded:                 isBase64Encoded,
+			MultiValueHeaders:               map[string][]string{},
+			MultiValueQueryStringParameters: map[string][]string{},
+			Path:                            r.URL.Path,
+			PathParameters:                  map[string]string{"proxy": strings.TrimPrefix(r.URL.Path, "/")},
+			QueryStringParameters:           map[string]string{},
+			RequestContext: events.APIGatewayProxyRequestContext{
+				AccountID:  gatewayAccountID,
+				APIID:      gatewayAPIID,
+				DomainName: r.Host,
+				HTTPMethod: r.Method,
+				Identity: events.APIGatewayRequestIdentity{
+					SourceIP:  sourceIP,
+					UserAgent: r.UserAgent(),
+				},
+				Path:             r.URL.Path,
+				Protocol:         r.Proto,
+				RequestID:        requestID,
+				RequestTime:      now.Format("02/Jan/2006:15:04:05 -0700"),
+				RequestTimeEpoch: now.UnixMilli(),
+				ResourcePath:     "/{proxy+}",
+				Stage:            "local",
+			},
+			Resource: "/{proxy+}",
+		}
+
+		for name, values := range r.Header {
+			event.Headers[name] = values[len(values)-1]
+			event.MultiValueHeaders[name] = values
+		}
+		for name, values := range r.URL.Query() {
+			event.QueryStringParameters[name] = values[len(values)-1]
+			event.MultiValueQueryStringParameters[name] = values
+		}
+
+		return json.Marshal(event)
+	case GatewayPayloadVersion2:
+		event := events.APIGatewayV2HTTPRequest{
+			Body:            body,
+			Headers:         map[string]string{},
+			IsBase64Encoded: isBase64Encoded,
+			RawPath:         r.URL.Path,
+			RawQueryString:  r.URL.RawQuery,
+			RequestContext: events.APIGatewayV2HTTPRequestContext{
+				AccountID:  gatewayAccountID,
+				APIID:      gatewayAPIID,
+				DomainName: r.Host,
+				HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+					Method:    r.Method,
+					Path:      r.URL.Path,
+					Protocol:  r.Proto,
+					SourceIP:  sourceIP,
+					UserAgent: r.UserAgent(),
+				},
+				RequestID: requestID,
+				RouteKey:  "$default",
+				Stage:     "$default",
+				Time:      now.Format("02/Jan/2006:15:04:05 -0700"),
+				TimeEpoch: now.UnixMilli(),
+			},
+			RouteKey: "$default",
+			Version:  GatewayPayloadVersion2,
+		}
+
+		// cookies are passed separately from the headers, and repeated headers are comma-joined
+		for name, values := range r.Header {
+			if name == "Cookie" {
+				for _, value := range values {
+					event.Cookies = append(event.Cookies, strings.Split(value, "; ")...)
+				}
+				continue
+			}
+
+			event.Headers[strings.ToLower(name)] = strings.Join(values, ",")
+		}
+
+		if query := r.URL.Query(); len(query) > 0 {
+			event.QueryStringParameters = map[string]string{}
+			for name, values := range query {
+				event.QueryStringParameters[name] = strings.Join(values, ",")
+			}
+		}
+
+		return json.Marshal(event)
+	}
+
+	return nil, fmt.Errorf("unsupported payload format version: %s", version)
+}
+
+// WriteGatewayResponse translates the response of a function invoked with an
+// API Gateway proxy event into an http response
+func WriteGatewayResponse(w http.ResponseWriter, payload []byte, version string) error {
+	// the http api response is a superset of the rest api response
+	var response events.APIGatewayV2HTTPResponse
+
+	// http apis accept a response without a status code, treating it as a json body
+	var fields map[string]json.RawMessage
+	structured := json.Unmarshal(payload, &fields) == nil && fields["statusCode"] != nil
+	if version == GatewayPayloadVersion2 && !structured {
+		response.StatusCode = http.StatusOK
+		response.Headers = map[string]string{"Content-Type": "application/json"}
+		response.Body = string(payload)
+	} else if err := json.Unmarshal(payload, &response); err != nil || response.StatusCode == 0 {
+		return fmt.Errorf("function returned a malformed proxy response")
+	}
+
+	body := []byte(response.Body)
+	if response.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(response.Body)
+		if err != nil {
+			return fmt.Errorf("error decoding response body: %w", err)
+		}
+		body = decoded
+	}
+
+	for name, value := range response.Headers {
+		w.Header().Set(name, value)
+	}
+	for name, values := range response.MultiValueHeaders {
+		w.Header().Del(name)
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	for _, cookie := range response.Cookies {
+		w.Header().Add("Set-Cookie", cookie)
+	}
+
+	w.WriteHeader(response.StatusCode)
+	_, err := w.Write(body)
+	return err
+}
@@ -1,6 +1,10 @@
 package builders
 
-import "lambda-builder/io"
+import (
+	"fmt"
+
+	"lambda-builder/io"
+)
 
 type GoBuilder struct {
 	Config Config
@@ -23,6 +27,9 @@ func NewGoBuilder(config Config) (GoBuilder, error) {
 		return GoBuilder{}, err
 	}
 
+	// the build script cross-compiles via GOARCH, so no emulation is needed
+	config.NativeBuild = true
+
 	return GoBuilder{
 		Config: config,
 	}, nil
@@ -41,7 +48,14 @@ func (b GoBuilder) Detect() bool {
 }
 
 func (b GoBuilder) Execute() error {
+	if b.Config.Layer {
+		return fmt.Errorf("the %s builder does not support layer builds", b.Name())
+	}
+
+	b.Config.Builder = b.Name()
+	b.Config.DependencyFiles = b.GetDependencyFiles()
 	b.Config.HandlerMap = b.GetHandlerMap()
+	b.Config.SlimPatterns = b.GetSlimPatterns()
 	return executeBuilder(b.script(), b.Config)
 }
 
@@ -53,16 +67,31 @@ func (b GoBuilder) GetConfig() Config {
 	return b.Config
 }
 
+func (b GoBuilder) GetDependencyFiles() []string {
+	return []string{
+		"go.mod",
+		"go.sum",
+	}
+}
+
 func (b GoBuilder) GetHandlerMap() map[string]string {
 	return map[string]string{
 		"bootstrap": "bootstrap",
 	}
 }
 
+func (b GoBuilder) GetSlimPatterns() []string {
+	return []string{}
+}
+
 func (b GoBuilder) Name() string {
 	return "go"
 }
 
+func (b GoBuilder) Shell() error {
+	return executeShell(b.script(), b.Config)
+}
+
 func (b GoBuilder) script() string {
 	return `
 #!/usr/bin/env bash
@@ -88,8 +117,13 @@ install-gomod() {
     go get
   fi
 
-  puts-step "Compiling via go build"
-  CGO_ENABLED=0 go build -o bootstrap main.go 2>&1 | indent
+  goarch="amd64"
+  if [[ "$LAMBDA_ARCHITECTURE" == "arm64" ]]; then
+    goarch="arm64"
+  fi
+
+  puts-step "Compiling via go build for linux/${goarch}"
+  CGO_ENABLED=0 GOOS=linux GOARCH="$goarch" go build -o bootstrap main.go 2>&1 | indent
 }
 
 hook-pre-compile() {
@@ -127,6 +161,12 @@ hook-package() {
   mv lambda.zip /var/task/lambda.zip
 }
 
+if [[ "$LAMBDA_BUILD_PHASE" == "dependencies" ]]; then
+  puts-step "Downloading dependencies via go mod"
+  go mod download 2>&1 | indent
+  exit 0
+fi
+
 cp -a /var/task/. /go/src/handler
 cd /go/src/handler
 hook-pre-compile
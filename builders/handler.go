@@ -18,6 +18,10 @@ func getFunctionHandler(directory string, config Config) string {
 		}
 	}
 
+	if config.HandlerFinder != nil {
+		return config.HandlerFinder(directory)
+	}
+
 	return ""
 }
 
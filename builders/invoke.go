@@ -0,0 +1,235 @@
+package builders
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultFunctionPort is the port docker-lambda images serve the invoke api on
+	defaultFunctionPort = "9001"
+
+	// functionStartTimeout is how long to wait for a started function to accept invocations
+	functionStartTimeout = 30 * time.Second
+)
+
+// FunctionContainer is a run image started in stay-open mode, accepting
+// invocations over the lambda invoke api
+type FunctionContainer struct {
+	// Endpoint is the base url of the invoke api
+	Endpoint string
+
+	// Handler is the function handler invoked
+	Handler string
+
+	// Name is the name of the container
+	Name string
+
+	client *http.Client
+	engine ContainerEngine
+}
+
+// InvokeResult contains the response to a single invocation
+type InvokeResult struct {
+	// Duration is the time taken by the invocation, as seen by the caller
+	Duration time.Duration
+
+	// FunctionError is the type of error raised by the function, if any
+	FunctionError string
+
+	// Logs is the tail of the logs output by the invocation
+	Logs string
+
+	// Payload is the response returned by the function
+	Payload []byte
+
+	// StatusCode is the http status code of the invoke api response
+	StatusCode int
+}
+
+// RunImageExists returns true if the run image for the config has been built
+func RunImageExists(config Config) (bool, error) {
+	engine, err := getContainerEngine(config)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := engine.InspectImage(context.Background(), config.GetImageTag()); err != nil {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// StartFunctionContainer starts the run image for the config in the background
+// with the given environment variables, returning once it accepts invocations.
+// The handler defaults to the command of the run image
+func StartFunctionContainer(ctx context.Context, config Config, env []string) (*FunctionContainer, error) {
+	return StartFunctionContainerOnPort(ctx, config, env, 0)
+}
+
+// StartFunctionContainerOnPort starts the run image for the config as
+// StartFunctionContainer does, publishing the invoke api on the given port
+// of the loopback interface. A free port is selected when the port is 0
+func StartFunctionContainerOnPort(ctx context.Context, config Config, env []string, hostPort int) (*FunctionContainer, error) {
+	engine, err := getContainerEngine(config)
+	if err != nil {
+		return nil, err
+	}
+
+	config.Architecture, err = getArchitecture(config)
+	if err != nil {
+		return nil, err
+	}
+
+	image := config.GetImageTag()
+	inspect, err := engine.InspectImage(ctx, image)
+	if err != nil {
+		return nil, fmt.Errorf("error inspecting run image %s: %w", image, err)
+	}
+
+	handler := config.Handler
+	if handler == "" && len(inspect.Config.Cmd) > 0 {
+		handler = inspect.Config.Cmd[0]
+	}
+	if handler == "" {
+		return nil, fmt.Errorf("unable to detect handler from run image %s", image)
+	}
+
+	containerPort := defaultFunctionPort
+	for _, e := range inspect.Config.Env {
+		if value, ok := strings.CutPrefix(e, "DOCKER_LAMBDA_API_PORT="); ok {
+			containerPort = value
+		}
+	}
+
+	if hostPort == 0 {
+		hostPort, err = getFreePort()
+		if err != nil {
+			return nil, fmt.Errorf("error selecting port for function: %w", err)
+		}
+	}
+
+	container := &FunctionContainer{
+		Endpoint: fmt.Sprintf("http://127.0.0.1:%d", hostPort),
+		Handler:  handler,
+		Name:     fmt.Sprintf("lambda-builder-invoke-%s", config.Identifier),
+		client:   &http.Client{},
+		engine:   engine,
+	}
+
+	// the handler is passed as the command so an override applies to an existing image
+	input := StartContainerInput{
+		Command:       []string{handler},
+		ContainerName: container.Name,
+		Env:           append([]string{"DOCKER_LAMBDA_STAY_OPEN=1"}, env...),
+		Image:         image,
+		Labels:        []string{"com.dokku.lambda-builder/invoker=true"},
+		Platform:      config.GetPlatform(),
+		Ports:         []string{fmt.Sprintf("127.0.0.1:%d:%s", hostPort, containerPort)},
+	}
+
+	if err := engine.StartContainer(ctx, input); err != nil {
+		container.Stop()
+		return nil, err
+	}
+
+	if err := container.waitForReady(ctx); err != nil {
+		container.Stop()
+		return nil, err
+	}
+
+	return container, nil
+}
+
+// Invoke sends an event to the function and waits for its response
+func (c *FunctionContainer) Invoke(ctx context.Context, event []byte) (InvokeResult, error) {
+	var result InvokeResult
+	url := fmt.Sprintf("%s/2015-03-31/functions/%s/invocations", c.Endpoint, c.Handler)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(event))
+	if err != nil {
+		return result, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Amz-Invocation-Type", "RequestResponse")
+	req.Header.Set("X-Amz-Log-Type", "Tail")
+
+	start := time.Now()
+	res, err := c.client.Do(req)
+	if err != nil {
+		return result, fmt.Errorf("error invoking function: %w", err)
+	}
+	defer res.Body.Close()
+
+	result.Payload, err = io.ReadAll(res.Body)
+	result.Duration = time.Since(start)
+	if err != nil {
+		return result, fmt.Errorf("error reading function response: %w", err)
+	}
+
+	result.FunctionError = res.Header.Get("X-Amz-Function-Error")
+	result.StatusCode = res.StatusCode
+	if logs := res.Header.Get("X-Amz-Log-Result"); logs != "" {
+		decoded, err := base64.StdEncoding.DecodeString(logs)
+		if err != nil {
+			return result, fmt.Errorf("error decoding function logs: %w", err)
+		}
+		result.Logs = string(decoded)
+	}
+
+	return result, nil
+}
+
+// Stop removes the container
+func (c *FunctionContainer) Stop() error {
+	return c.engine.RemoveContainer(context.Background(), c.Name)
+}
+
+// waitForReady polls the invoke api until it responds. Any http response is
+// accepted, as published ports accept connections before the api is listening
+func (c *FunctionContainer) waitForReady(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, functionStartTimeout)
+	defer cancel()
+
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Endpoint, nil)
+		if err != nil {
+			return err
+		}
+
+		res, err := c.client.Do(req)
+		if err == nil {
+			res.Body.Close()
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("function did not start within %s", functionStartTimeout)
+			}
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// getFreePort returns a port on the loopback interface that is not in use
+func getFreePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,410 @@
+package builders
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"lambda-builder/io"
+)
+
+// javaVersionPatterns match the java version targeted by a maven or gradle build
+var javaVersionPatterns = []*regexp.Regexp{
+	// maven properties and compiler plugin configuration
+	regexp.MustCompile(`<(?:maven\.compiler\.release|maven\.compiler\.target|java\.version|release)>\s*(?:1\.)?(\d+)\s*<`),
+	// gradle toolchains
+	regexp.MustCompile(`JavaLanguageVersion\.of\(\s*(\d+)\s*\)`),
+	// gradle source and target compatibility
+	regexp.MustCompile(`(?:sourceCompatibility|targetCompatibility)\s*=?\s*(?:JavaVersion\.VERSION_)?['"]?(?:1[._])?(\d+)`),
+}
+
+type JavaBuilder struct {
+	Config Config
+}
+
+func NewJavaBuilder(config Config) (JavaBuilder, error) {
+	var err error
+	version := "21"
+	if config.BuilderBuildImage == "" || config.BuilderRunImage == "" {
+		version, err = parseJavaVersion(config.WorkingDirectory, []string{"8.al2", "11", "17", "21"})
+		if err != nil {
+			return JavaBuilder{}, err
+		}
+	}
+
+	config.BuilderBuildImage, err = getBuildImage(config, fmt.Sprintf("mlupin/docker-lambda:java%s-build", version))
+	if err != nil {
+		return JavaBuilder{}, err
+	}
+
+	config.BuilderRunImage, err = getRunImage(config, fmt.Sprintf("mlupin/docker-lambda:java%s", version))
+	if err != nil {
+		return JavaBuilder{}, err
+	}
+
+	return JavaBuilder{
+		Config: config,
+	}, nil
+}
+
+func (b JavaBuilder) Detect() bool {
+	if io.FileExistsInDirectory(b.Config.WorkingDirectory, "pom.xml") {
+		return true
+	}
+
+	if io.FileExistsInDirectory(b.Config.WorkingDirectory, "build.gradle") {
+		return true
+	}
+
+	if io.FileExistsInDirectory(b.Config.WorkingDirectory, "build.gradle.kts") {
+		return true
+	}
+
+	return false
+}
+
+func (b JavaBuilder) Execute() error {
+	b.Config.Builder = b.Name()
+	b.Config.DependencyFiles = b.GetDependencyFiles()
+	b.Config.HandlerFinder = findJavaHandler
+	b.Config.HandlerMap = b.GetHandlerMap()
+	b.Config.SlimPatterns = b.GetSlimPatterns()
+	return executeBuilder(b.script(), b.Config)
+}
+
+func (b JavaBuilder) GetBuildImage() string {
+	return b.Config.BuilderBuildImage
+}
+
+func (b JavaBuilder) GetConfig() Config {
+	return b.Config
+}
+
+func (b JavaBuilder) GetDependencyFiles() []string {
+	return []string{
+		"build.gradle",
+		"build.gradle.kts",
+		"gradle.properties",
+		"pom.xml",
+		"settings.gradle",
+		"settings.gradle.kts",
+	}
+}
+
+// GetHandlerMap returns no files, as handlers are found in the compiled classes by findJavaHandler
+func (b JavaBuilder) GetHandlerMap() map[string]string {
+	return map[string]string{}
+}
+
+func (b JavaBuilder) GetSlimPatterns() []string {
+	return []string{
+		"META-INF/maven",
+	}
+}
+
+func (b JavaBuilder) Name() string {
+	return "java"
+}
+
+func (b JavaBuilder) Shell() error {
+	return executeShell(b.script(), b.Config)
+}
+
+func (b JavaBuilder) script() string {
+	return `
+#!/usr/bin/env bash
+set -eo pipefail
+
+[ "$BUILDER_XTRACE" ] && set -o xtrace
+
+indent() {
+  sed -u "s/^/       /"
+}
+
+puts-step() {
+  echo "-----> $*"
+}
+
+run-maven() {
+  if [[ -f "mvnw" ]]; then
+    chmod +x mvnw
+    ./mvnw --batch-mode "$@"
+  else
+    mvn --batch-mode "$@"
+  fi
+}
+
+run-gradle() {
+  if [[ -f "gradlew" ]]; then
+    chmod +x gradlew
+    ./gradlew --no-daemon --console=plain "$@"
+  else
+    gradle --no-daemon --console=plain "$@"
+  fi
+}
+
+write-gradle-init-script() {
+  cat >/tmp/lambda-builder.gradle <<'EOF'
+rootProject {
+  afterEvaluate { project ->
+    project.tasks.register("lambdaBuilderLib", Copy) {
+      from project.configurations.runtimeClasspath
+      into "/tmp/lambda-builder/lib"
+    }
+  }
+}
+EOF
+}
+
+extract-jar() {
+  puts-step "Extracting $1"
+  mkdir -p /tmp/lambda-builder/function
+  pushd /tmp/lambda-builder/function >/dev/null || return 1
+  jar xf "/var/task/$1"
+  popd >/dev/null || return 1
+}
+
+copy-classes() {
+  mkdir -p /tmp/lambda-builder/function
+  for dir in "$@"; do
+    if [[ -d "$dir" ]]; then
+      cp -a "$dir/." /tmp/lambda-builder/function/
+    fi
+  done
+}
+
+compile-maven() {
+  if [[ "$LAMBDA_BUILD_LAYER" != "1" ]] && grep -q "maven-shade-plugin" pom.xml; then
+    puts-step "Packaging shaded jar via maven"
+    run-maven package -DskipTests 2>&1 | indent
+    extract-jar "$(find target -maxdepth 1 -name "*.jar" ! -name "original-*" ! -name "*-sources.jar" ! -name "*-javadoc.jar" | head -n1)"
+    return
+  fi
+
+  puts-step "Compiling via maven"
+  run-maven compile dependency:copy-dependencies -DincludeScope=runtime -DoutputDirectory=/tmp/lambda-builder/lib 2>&1 | indent
+  copy-classes target/classes
+}
+
+compile-gradle() {
+  if [[ "$LAMBDA_BUILD_LAYER" != "1" ]] && grep -Eqs "(johnrengelman|gradleup|goooler)\.shadow" build.gradle build.gradle.kts; then
+    puts-step "Packaging shaded jar via gradle"
+    run-gradle shadowJar -x test 2>&1 | indent
+    extract-jar "$(find build/libs -maxdepth 1 -name "*-all.jar" | head -n1)"
+    return
+  fi
+
+  puts-step "Compiling via gradle"
+  write-gradle-init-script
+  run-gradle --init-script /tmp/lambda-builder.gradle classes lambdaBuilderLib 2>&1 | indent
+  copy-classes build/classes/java/main build/classes/kotlin/main build/resources/main
+}
+
+compile() {
+  if [[ -f "pom.xml" ]]; then
+    compile-maven
+  else
+    compile-gradle
+  fi
+}
+
+install-dependencies() {
+  if [[ -f "pom.xml" ]]; then
+    puts-step "Downloading dependencies via maven"
+    run-maven dependency:go-offline 2>&1 | indent
+  else
+    puts-step "Downloading dependencies via gradle"
+    write-gradle-init-script
+    run-gradle --init-script /tmp/lambda-builder.gradle lambdaBuilderLib 2>&1 | indent
+    rm -rf /tmp/lambda-builder
+  fi
+}
+
+hook-pre-compile() {
+  if [[ ! -f bin/pre_compile ]]; then
+    return
+  fi
+
+  puts-step "Running pre-compile hook"
+  chmod +x bin/pre_compile
+  bin/pre_compile
+}
+
+hook-post-compile() {
+  if [[ ! -f bin/post_compile ]]; then
+    return
+  fi
+
+  puts-step "Running post-compile hook"
+  chmod +x bin/post_compile
+  bin/post_compile
+}
+
+package-layer() {
+  puts-step "Creating layer at layer.zip"
+  mkdir -p /tmp/layer/java/lib
+  if [[ -d /tmp/lambda-builder/lib ]]; then
+    cp -a /tmp/lambda-builder/lib/. /tmp/layer/java/lib/
+  fi
+  pushd /tmp/layer >/dev/null || return 1
+  zip -q -r /var/task/layer.zip java
+  popd >/dev/null || return 1
+}
+
+hook-package() {
+  if [[ "$LAMBDA_BUILD_ZIP" != "1" ]]; then
+    return
+  fi
+
+  if [[ "$LAMBDA_BUILD_LAYER" == "1" ]]; then
+    package-layer
+    if [[ "$LAMBDA_BUILD_LAYER_FUNCTION" != "1" ]]; then
+      return
+    fi
+  elif [[ -d /tmp/lambda-builder/lib ]]; then
+    cp -a /tmp/lambda-builder/lib /tmp/lambda-builder/function/lib
+  fi
+
+  puts-step "Creating package at lambda.zip"
+  pushd /tmp/lambda-builder/function >/dev/null || return 1
+  zip -q -r /var/task/lambda.zip .
+  popd >/dev/null || return 1
+}
+
+if [[ "$LAMBDA_BUILD_PHASE" == "dependencies" ]]; then
+  install-dependencies
+  exit 0
+fi
+
+hook-pre-compile
+compile
+hook-post-compile
+hook-package
+`
+}
+
+// parseJavaVersion returns the earliest supported java runtime able to run
+// the version targeted by the maven or gradle build, defaulting to the latest
+func parseJavaVersion(workingDirectory string, supportedJavaVersions []string) (string, error) {
+	for _, file := range []string{"pom.xml", "build.gradle", "build.gradle.kts"} {
+		if !io.FileExistsInDirectory(workingDirectory, file) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(workingDirectory, file))
+		if err != nil {
+			return "", fmt.Errorf("error reading %s: %w", file, err)
+		}
+
+		for _, pattern := range javaVersionPatterns {
+			match := pattern.FindSubmatch(data)
+			if match == nil {
+				continue
+			}
+
+			requested, err := strconv.Atoi(string(match[1]))
+			if err != nil {
+				return "", fmt.Errorf("error parsing java version from %s: %w", file, err)
+			}
+
+			for _, version := range supportedJavaVersions {
+				major, err := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+				if err != nil {
+					return "", fmt.Errorf("error parsing supported java version '%s': %s", version, err.Error())
+				}
+
+				if major >= requested {
+					return version, nil
+				}
+			}
+
+			return "", fmt.Errorf("unsupported java version %d targeted in %s", requested, file)
+		}
+	}
+
+	return supportedJavaVersions[len(supportedJavaVersions)-1], nil
+}
+
+// javaHandlerInterface is the package of the RequestHandler and
+// RequestStreamHandler interfaces, as referenced within class files
+var javaHandlerInterface = []byte("com/amazonaws/services/lambda/runtime/Request")
+
+// javaHandlerClassNames are the class names used as a handler when
+// no class implementing a handler interface is found
+var javaHandlerClassNames = map[string]bool{
+	"App.class":      true,
+	"Function.class": true,
+	"Handler.class":  true,
+}
+
+// findJavaHandler returns the handler for the compiled classes within directory.
+// Classes implementing RequestHandler or RequestStreamHandler are preferred over
+// classes named App, Function, or Handler, at any package depth, with classes
+// in shallower packages and then lexically earlier paths winning ties
+func findJavaHandler(directory string) string {
+	implementing := []string{}
+	named := []string{}
+	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			if rel == "lib" || rel == "META-INF" || rel == "com/amazonaws" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !strings.HasSuffix(rel, ".class") || strings.Contains(filepath.Base(rel), "$") {
+			return nil
+		}
+
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		if bytes.Contains(contents, javaHandlerInterface) {
+			implementing = append(implementing, rel)
+		} else if javaHandlerClassNames[filepath.Base(rel)] {
+			named = append(named, rel)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return ""
+	}
+
+	candidates := implementing
+	if len(candidates) == 0 {
+		candidates = named
+	}
+
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		depthI, depthJ := strings.Count(candidates[i], "/"), strings.Count(candidates[j], "/")
+		if depthI != depthJ {
+			return depthI < depthJ
+		}
+		return candidates[i] < candidates[j]
+	})
+
+	class := strings.ReplaceAll(strings.TrimSuffix(candidates[0], ".class"), "/", ".")
+	return fmt.Sprintf("%s::handleRequest", class)
+}
@@ -0,0 +1,75 @@
+package builders
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeClassFiles writes the named class files with the given contents
+func writeClassFiles(t *testing.T, directory string, files map[string]string) {
+	t.Helper()
+
+	for name, contents := range files {
+		path := filepath.Join(directory, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindJavaHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected string
+	}{
+		{
+			name: "implementing class at any depth",
+			files: map[string]string{
+				"com/acme/orders/OrderProcessor.class": "com/amazonaws/services/lambda/runtime/RequestHandler",
+				"com/acme/orders/Util.class":           "java/lang/Object",
+			},
+			expected: "com.acme.orders.OrderProcessor::handleRequest",
+		},
+		{
+			name: "implementing class preferred over named class",
+			files: map[string]string{
+				"Handler.class":             "java/lang/Object",
+				"com/acme/StreamFunc.class": "com/amazonaws/services/lambda/runtime/RequestStreamHandler",
+			},
+			expected: "com.acme.StreamFunc::handleRequest",
+		},
+		{
+			name: "named class when nothing implements a handler interface",
+			files: map[string]string{
+				"com/acme/Handler.class": "java/lang/Object",
+				"com/acme/Util.class":    "java/lang/Object",
+			},
+			expected: "com.acme.Handler::handleRequest",
+		},
+		{
+			name: "dependencies and inner classes are ignored",
+			files: map[string]string{
+				"com/acme/Handler$1.class":                                   "com/amazonaws/services/lambda/runtime/RequestHandler",
+				"com/amazonaws/services/lambda/runtime/RequestHandler.class": "com/amazonaws/services/lambda/runtime/RequestHandler",
+				"lib/Handler.class":                                          "java/lang/Object",
+			},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			directory := t.TempDir()
+			writeClassFiles(t, directory, tt.files)
+			if handler := findJavaHandler(directory); handler != tt.expected {
+				t.Errorf("expected handler %q, got %q", tt.expected, handler)
+			}
+		})
+	}
+}
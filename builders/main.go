@@ -7,14 +7,15 @@ import (
 	"html/template"
 	"io/ioutil"
 	"os"
-	"os/signal"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"lambda-builder/io"
+	"lambda-builder/ui"
 
-	execute "github.com/alexellis/go-execute/pkg/v2"
 	extract "github.com/codeclysm/extract/v4"
 	"gopkg.in/yaml.v2"
 )
@@ -24,28 +25,68 @@ type Builder interface {
 	Execute() error
 	GetBuildImage() string
 	GetConfig() Config
+	GetDependencyFiles() []string
 	GetHandlerMap() map[string]string
+	GetSlimPatterns() []string
 	Name() string
+	Shell() error
 }
 
 type Config struct {
+	Architecture      string
 	BuildEnv          []string
 	Builder           string
 	BuilderBuildImage string
 	BuilderRunImage   string
+	Cache             bool
+	ContainerEngine   ContainerEngine
+	DependencyFiles   []string
+	Engine            string
+	FailOnSizeLimit   bool
 	GenerateRunImage  bool
 	Handler           string
+	HandlerFinder     func(directory string) string
 	HandlerMap        map[string]string
 	Identifier        string
 	ImageEnv          []string
 	ImageLabels       []string
 	ImageTag          string
+	KeepBuildImage    bool
+	Layer             bool
+	LayerFunction     bool
+	LayerOutput       string
+	Logger            *ui.ZerologUi
+	MaxUnzippedSize   int64
+	MaxZipSize        int64
+	NativeBuild       bool
+	Output            string
 	Port              int
+	ReuseBuildImage   bool
 	RunQuiet          bool
+	Slim              bool
+	SlimPatterns      []string
 	WorkingDirectory  string
 	WriteProcfile     bool
 }
 
+// GetArtifacts returns the names of the zip files produced by the build
+func (c Config) GetArtifacts() []string {
+	if !c.Layer {
+		return []string{"lambda.zip"}
+	}
+
+	if c.LayerFunction {
+		return []string{"layer.zip", "lambda.zip"}
+	}
+
+	return []string{"layer.zip"}
+}
+
+// HasFunctionArtifact returns true if the build produces a function zip
+func (c Config) HasFunctionArtifact() bool {
+	return !c.Layer || c.LayerFunction
+}
+
 func (c Config) GetImageTag() string {
 	if c.ImageTag != "" {
 		return c.ImageTag
@@ -55,17 +96,147 @@ func (c Config) GetImageTag() string {
 	return fmt.Sprintf("lambda-builder/%s:latest", appName)
 }
 
+// GetBuildImageTag returns the tag of the intermediate build image. Build images
+// kept between builds use a stable tag, while all others are unique to the build
+// so that concurrent builds of the same app do not clobber each others images
+func (c Config) GetBuildImageTag() string {
+	if c.Cache || c.KeepBuildImage || c.ReuseBuildImage || c.Identifier == "" {
+		return fmt.Sprintf("%s-build", c.GetImageTag())
+	}
+
+	return fmt.Sprintf("%s-build-%s", c.GetImageTag(), c.Identifier)
+}
+
+// GetLogger returns the logger for the build, defaulting to one without fields
+func (c Config) GetLogger() *ui.ZerologUi {
+	if c.Logger != nil {
+		return c.Logger
+	}
+
+	return ui.ZerologUiWithFields(nil, map[string]interface{}{})
+}
+
+// GetImageVersion returns the tag portion of the image tag
+func (c Config) GetImageVersion() string {
+	imageTag := c.GetImageTag()
+	if i := strings.LastIndex(imageTag, ":"); i > strings.LastIndex(imageTag, "/") {
+		return imageTag[i+1:]
+	}
+
+	return "latest"
+}
+
 type LambdaYML struct {
-	Builder    string `yaml:"builder"`
-	BuildImage string `yaml:"build_image"`
-	RunImage   string `yaml:"run_image"`
+	Architecture    string              `yaml:"architecture"`
+	Builder         string              `yaml:"builder"`
+	Builders        []BuilderDefinition `yaml:"builders"`
+	BuildImage      string              `yaml:"build_image"`
+	Cache           bool                `yaml:"cache"`
+	Engine          string              `yaml:"engine"`
+	Exclude         []string            `yaml:"exclude"`
+	FailOnSizeLimit bool                `yaml:"fail_on_size_limit"`
+	LayerOutput     string              `yaml:"layer_output"`
+	MaxUnzippedSize int64               `yaml:"max_unzipped_size"`
+	MaxZipSize      int64               `yaml:"max_zip_size"`
+	Output          string              `yaml:"output"`
+	RunImage        string              `yaml:"run_image"`
+	Runtime         string              `yaml:"runtime"`
+	RustBinary      string              `yaml:"rust_binary"`
+	RustLibc        string              `yaml:"rust_libc"`
+	Slim            bool                `yaml:"slim"`
+	SlimKeep        []string            `yaml:"slim_keep"`
 }
 
 func executeBuilder(script string, config Config) error {
-	if err := executeBuildContainer(script, config); err != nil {
+	start := time.Now()
+	logger := config.GetLogger()
+	engine, err := getContainerEngine(config)
+	if err != nil {
+		return err
+	}
+
+	config.Architecture, err = getArchitecture(config)
+	if err != nil {
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("Building for %s architecture", config.GetArchitecture()))
+	if !config.NativeBuild {
+		checkEmulation(logger, config.GetArchitecture())
+	}
+
+	config.Slim, err = getSlim(config)
+	if err != nil {
+		return err
+	}
+
+	artifactPaths, err := getArtifactPaths(config)
+	if err != nil {
+		return err
+	}
+
+	temporaryPaths := map[string]string{}
+	for artifact, path := range artifactPaths {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("error creating output directory: %w", err)
+		}
+
+		temporaryPaths[artifact] = getTemporaryArtifactPath(config, path)
+		defer os.Remove(temporaryPaths[artifact])
+	}
+
+	// a manifest left by a previous build must not describe the new artifacts
+	manifestPath := getManifestPath(config, artifactPaths)
+	if err := os.Remove(manifestPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing existing build manifest: %w", err)
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	if err := checkImagePlatform(ctx, engine, config.BuilderBuildImage, config.GetBuildPlatform()); err != nil {
+		return newBuildError(PhaseBuild, err)
+	}
+
+	if config.GenerateRunImage {
+		if err := checkImagePlatform(ctx, engine, config.BuilderRunImage, config.GetPlatform()); err != nil {
+			return newBuildError(PhaseImage, err)
+		}
+	}
+
+	if err := executeBuildContainer(ctx, engine, script, config, temporaryPaths); err != nil {
 		return err
 	}
 
+	modTime, err := io.ZipModTime()
+	if err != nil {
+		return newBuildError(PhasePackage, err)
+	}
+
+	if config.Layer {
+		layerDir, err := os.MkdirTemp("", "lambda-builder-layer")
+		if err != nil {
+			return fmt.Errorf("error creating layer dir: %w", err)
+		}
+
+		defer func() {
+			os.RemoveAll(layerDir)
+		}()
+
+		logger.LogHeader2("Packaging layer.zip")
+		if err := repackArtifact(config, "layer.zip", temporaryPaths["layer.zip"], layerDir, modTime); err != nil {
+			return err
+		}
+	}
+
+	if !config.HasFunctionArtifact() {
+		if err := moveArtifacts(temporaryPaths, artifactPaths); err != nil {
+			return err
+		}
+
+		return writeManifest(ctx, engine, config, artifactPaths, manifestPath, "", start)
+	}
+
 	taskHostBuildDir, err := os.MkdirTemp("", "lambda-builder")
 	if err != nil {
 		return fmt.Errorf("error creating build dir: %w", err)
@@ -75,36 +246,37 @@ func executeBuilder(script string, config Config) error {
 		os.RemoveAll(taskHostBuildDir)
 	}()
 
-	fmt.Printf("-----> Extracting lambda.zip into build context dir\n")
-	zipPath := filepath.Join(config.WorkingDirectory, "lambda.zip")
-	data, _ := ioutil.ReadFile(zipPath)
-	buffer := bytes.NewBuffer(data)
-	if err := extract.Zip(context.Background(), buffer, taskHostBuildDir, nil); err != nil {
-		return fmt.Errorf("error extracting lambda.zip into build context dir: %w", err)
+	logger.LogHeader2("Packaging lambda.zip")
+	if err := repackArtifact(config, "lambda.zip", temporaryPaths["lambda.zip"], taskHostBuildDir, modTime); err != nil {
+		return err
 	}
 
 	handler := getFunctionHandler(taskHostBuildDir, config)
 	if config.WriteProcfile && !io.FileExistsInDirectory(taskHostBuildDir, "Procfile") {
 		if handler == "" {
-			fmt.Printf(" !     Unable to detect handler in build directory\n")
+			logger.Warn("Unable to detect handler in build directory")
 		} else {
-			fmt.Printf("=====> Writing Procfile from handler: %s\n", handler)
+			logger.LogHeader1(fmt.Sprintf("Writing Procfile from handler: %s", handler))
 
-			fmt.Printf("       Writing to working directory\n")
+			logger.Info("Writing to working directory")
 			if err := writeProcfile(handler, config.WorkingDirectory); err != nil {
 				return fmt.Errorf("error writing Procfile to working directory: %w", err)
 			}
 
-			fmt.Printf("       Writing to build directory\n")
+			logger.Info("Writing to build directory")
 			if err := writeProcfile(handler, taskHostBuildDir); err != nil {
 				return fmt.Errorf("error writing Procfile to temporary build directory: %w", err)
 			}
 		}
 	}
 
+	if err := moveArtifacts(temporaryPaths, artifactPaths); err != nil {
+		return err
+	}
+
 	if config.GenerateRunImage {
-		fmt.Printf("=====> Building image\n")
-		fmt.Printf("       Generating temporary Dockerfile\n")
+		logger.LogHeader1("Building image")
+		logger.Info("Generating temporary Dockerfile")
 
 		dockerfilePath, err := ioutil.TempFile("", "lambda-builder")
 		defer func() {
@@ -119,31 +291,69 @@ func executeBuilder(script string, config Config) error {
 			return err
 		}
 
-		fmt.Printf("       Executing build of %s\n", config.GetImageTag())
-		if err := buildDockerImage(taskHostBuildDir, config, "run", dockerfilePath); err != nil {
-			return err
+		logger.Info(fmt.Sprintf("Executing build of %s", config.GetImageTag()))
+		if err := buildDockerImage(ctx, engine, taskHostBuildDir, config, "run", dockerfilePath); err != nil {
+			return newBuildError(PhaseImage, err)
 		}
 	}
 
+	return writeManifest(ctx, engine, config, artifactPaths, manifestPath, handler, start)
+}
+
+// writeManifest writes the build manifest for the completed build
+func writeManifest(ctx context.Context, engine ContainerEngine, config Config, artifactPaths map[string]string, manifestPath string, handler string, start time.Time) error {
+	config.GetLogger().Info(fmt.Sprintf("Writing build manifest to %s", manifestPath))
+	manifest, err := newBuildManifest(ctx, engine, config, artifactPaths, handler, time.Since(start))
+	if err != nil {
+		return newBuildError(PhasePackage, err)
+	}
+
+	if err := writeBuildManifest(manifest, manifestPath); err != nil {
+		return newBuildError(PhasePackage, err)
+	}
+
 	return nil
 }
 
-func executeBuildContainer(script string, config Config) error {
-	fmt.Printf("       Generating temporary build script\n")
-	scriptPath, err := os.Create(filepath.Join(config.WorkingDirectory, ".lambda-builder"))
+func executeBuildContainer(ctx context.Context, engine ContainerEngine, script string, config Config, destinations map[string]string) error {
+	logger := config.GetLogger()
+	logger.Info("Preparing build context")
+	matcher, err := getIgnoreMatcher(config)
+	if err != nil {
+		return err
+	}
+
+	buildContextDir, err := os.MkdirTemp("", "lambda-builder-context")
+	if err != nil {
+		return fmt.Errorf("error creating build context dir: %w", err)
+	}
+
 	defer func() {
-		os.Remove(scriptPath.Name())
+		os.RemoveAll(buildContextDir)
 	}()
 
+	if err := io.CopyDirectory(config.WorkingDirectory, buildContextDir, matcher); err != nil {
+		return fmt.Errorf("error copying app into build context dir: %w", err)
+	}
+
+	// the app's ignore patterns have already been applied, and the cli
+	// engines would otherwise apply the .dockerignore to the context again
+	if err := os.Remove(filepath.Join(buildContextDir, ".dockerignore")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing .dockerignore from build context dir: %w", err)
+	}
+
+	logger.Info("Generating temporary build script")
+	scriptPath, err := os.Create(filepath.Join(buildContextDir, ".lambda-builder"))
 	if err != nil {
 		return fmt.Errorf("error generating temporary build script: %w", err)
 	}
+	defer scriptPath.Close()
 
 	if _, err := scriptPath.WriteString(strings.TrimSpace(script)); err != nil {
 		return err
 	}
 
-	fmt.Printf("       Generating temporary Dockerfile\n")
+	logger.Info("Generating temporary Dockerfile")
 	dockerfilePath, err := ioutil.TempFile("", "lambda-builder")
 	defer func() {
 		os.Remove(dockerfilePath.Name())
@@ -153,60 +363,71 @@ func executeBuildContainer(script string, config Config) error {
 		return fmt.Errorf("error generating temporary Dockerfile: %w", err)
 	}
 
-	if err := generateBuildDockerfile(config, dockerfilePath, scriptPath); err != nil {
+	cache, err := getCache(config)
+	if err != nil {
 		return err
 	}
+	config.Cache = cache
+
+	dependencyFiles := []string{}
+	if cache {
+		dependencyFiles, err = resolveDependencyFiles(buildContextDir, config.DependencyFiles)
+		if err != nil {
+			return err
+		}
+	}
 
-	fmt.Printf("       Executing build of %s\n", config.GetImageTag())
-	if err := buildDockerImage(config.WorkingDirectory, config, "build", dockerfilePath); err != nil {
+	if err := generateBuildDockerfile(config, dockerfilePath, scriptPath, dependencyFiles); err != nil {
 		return err
 	}
 
+	logger.Info(fmt.Sprintf("Executing build of %s", config.GetImageTag()))
+	if err := buildDockerImage(ctx, engine, buildContextDir, config, "build", dockerfilePath); err != nil {
+		return newBuildError(PhaseBuild, err)
+	}
+
 	defer func() {
-		buildImageTag := fmt.Sprintf("%s-build", config.GetImageTag())
-		fmt.Printf("       Removing build image: %s", buildImageTag)
-		args := []string{
-			"image",
-			"rm",
-			"--force",
-			buildImageTag,
+		buildImageTag := config.GetBuildImageTag()
+		if cache {
+			logger.Info(fmt.Sprintf("Keeping build image for dependency caching: %s", buildImageTag))
+			return
 		}
 
-		signals := make(chan os.Signal, 1)
-		signal.Notify(signals, os.Interrupt)
-		ctx, cancel := context.WithCancel(context.Background())
-		go func() {
-			<-signals
-			cancel()
-		}()
-
-		cmd := execute.ExecTask{
-			Args:        args,
-			Command:     "docker",
-			Cwd:         config.WorkingDirectory,
-			StreamStdio: !config.RunQuiet,
+		if config.KeepBuildImage {
+			logger.Info(fmt.Sprintf("Keeping build image: %s", buildImageTag))
+			return
 		}
 
-		if _, err := cmd.Execute(ctx); err != nil {
-			fmt.Printf("       Error cleaning up build image: %s", err.Error())
+		logger.Info(fmt.Sprintf("Removing build image: %s", buildImageTag))
+		if err := engine.RemoveImage(context.Background(), buildImageTag); err != nil {
+			logger.Warn(fmt.Sprintf("Error cleaning up build image: %s", err.Error()))
 		}
 	}()
 
-	extractLambdaFromBuildImage(config)
+	for _, artifact := range config.GetArtifacts() {
+		if err := extractArtifactFromBuildImage(ctx, engine, config, artifact, destinations[artifact]); err != nil {
+			return newBuildError(PhaseExtract, err)
+		}
+	}
 
 	return nil
 }
 
-func generateBuildDockerfile(config Config, dockerfilePath *os.File, scriptPath *os.File) error {
+func generateBuildDockerfile(config Config, dockerfilePath *os.File, scriptPath *os.File, dependencyFiles []string) error {
 	tpl, err := template.New("t1").Parse(`
 FROM {{ .build_image }}
 LABEL com.dokku.lambda-builder/builder={{ .builder_name }}
-ENV LAMBDA_BUILD_ZIP=1
 WORKDIR /var/task
-COPY . /var/task
 {{range .env}}
 ENV {{.}}
 {{end}}
+{{ if .dependency_files }}
+COPY {{ .build_script_name }} /usr/local/bin/build-lambda
+COPY {{range .dependency_files}}{{.}} {{end}}/var/task/
+RUN chmod +x /usr/local/bin/build-lambda && \
+	LAMBDA_BUILD_PHASE=dependencies /usr/local/bin/build-lambda
+{{ end }}
+COPY . /var/task
 RUN mv {{ .build_script_name }} /usr/local/bin/build-lambda && \
 	chmod +x /usr/local/bin/build-lambda && \
 	head -n1 /usr/local/bin/build-lambda && \
@@ -218,7 +439,8 @@ RUN mv {{ .build_script_name }} /usr/local/bin/build-lambda && \
 
 	data := map[string]interface{}{
 		"build_script_name": filepath.Base(scriptPath.Name()),
-		"env":               config.BuildEnv,
+		"dependency_files":  dependencyFiles,
+		"env":               getBuildEnv(config),
 		"builder":           config.Builder,
 		"build_image":       config.BuilderBuildImage,
 	}
@@ -230,40 +452,83 @@ RUN mv {{ .build_script_name }} /usr/local/bin/build-lambda && \
 	return nil
 }
 
-func extractLambdaFromBuildImage(config Config) error {
-	args := []string{
-		"container",
-		"run",
-		"--rm",
-		"--label", "com.dokku.lambda-builder/extractor=true",
-		"--name", fmt.Sprintf("lambda-builder-extractor-%s", config.Identifier),
-		"--volume", fmt.Sprintf("%s:/tmp/task", config.WorkingDirectory),
+// getBuildEnv returns the environment the build script is run with,
+// both when building and within a shell in the build image
+func getBuildEnv(config Config) []string {
+	env := []string{
+		fmt.Sprintf("LAMBDA_ARCHITECTURE=%s", config.GetArchitecture()),
+		"LAMBDA_BUILD_ZIP=1",
+	}
+
+	if config.Layer {
+		env = append(env, "LAMBDA_BUILD_LAYER=1")
+		if config.LayerFunction {
+			env = append(env, "LAMBDA_BUILD_LAYER_FUNCTION=1")
+		}
 	}
 
-	args = append(args, fmt.Sprintf("%s-build", config.GetImageTag()), "/bin/bash", "-c", "mv /var/task/lambda.zip /tmp/task/lambda.zip")
+	if config.Slim {
+		env = append(env, "LAMBDA_BUILD_SLIM=1")
+	}
 
-	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, os.Interrupt)
-	ctx, cancel := context.WithCancel(context.Background())
-	go func() {
-		<-signals
-		cancel()
-	}()
+	return append(env, config.BuildEnv...)
+}
+
+func extractArtifactFromBuildImage(ctx context.Context, engine ContainerEngine, config Config, artifact string, destination string) error {
+	output, flush := newOutputWriter(config, PhaseExtract)
+	defer flush()
 
-	cmd := execute.ExecTask{
-		Args:        args,
-		Command:     "docker",
-		Cwd:         config.WorkingDirectory,
-		StreamStdio: !config.RunQuiet,
+	input := CopyFromImageInput{
+		ContainerName: fmt.Sprintf("lambda-builder-extractor-%s", config.Identifier),
+		Destination:   destination,
+		Image:         config.GetBuildImageTag(),
+		Labels:        []string{"com.dokku.lambda-builder/extractor=true"},
+		Output:        output,
+		Platform:      config.GetBuildPlatform(),
+		Source:        filepath.Join("/var/task", artifact),
 	}
 
-	res, err := cmd.Execute(ctx)
+	if err := engine.CopyFromImage(ctx, input); err != nil {
+		return fmt.Errorf("error extracting %s: %w", artifact, err)
+	}
+
+	return nil
+}
+
+// repackArtifact extracts the artifact at path into directory and
+// rewrites it as a deterministic zip file from the extracted contents,
+// slimming the contents first if requested
+func repackArtifact(config Config, artifact string, path string, directory string, modTime time.Time) error {
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("error executing builder: %w", err)
+		return newBuildError(PhasePackage, fmt.Errorf("error reading %s: %w", artifact, err))
+	}
+
+	buffer := bytes.NewBuffer(data)
+	if err := extract.Zip(context.Background(), buffer, directory, nil); err != nil {
+		return newBuildError(PhasePackage, fmt.Errorf("error extracting %s: %w", artifact, err))
+	}
+
+	if config.Slim {
+		if err := slimArtifact(config, artifact, directory); err != nil {
+			return err
+		}
 	}
 
-	if res.ExitCode != 0 {
-		return fmt.Errorf("error executing builder, exit code %d", res.ExitCode)
+	if err := io.WriteDeterministicZip(directory, path, modTime); err != nil {
+		return newBuildError(PhasePackage, fmt.Errorf("error writing %s: %w", artifact, err))
+	}
+
+	return nil
+}
+
+// moveArtifacts moves each extracted artifact into place, replacing any
+// artifact left by a previous build
+func moveArtifacts(temporaryPaths map[string]string, artifactPaths map[string]string) error {
+	for artifact, temporaryPath := range temporaryPaths {
+		if err := os.Rename(temporaryPath, artifactPaths[artifact]); err != nil {
+			return newBuildError(PhasePackage, fmt.Errorf("error writing %s: %w", artifact, err))
+		}
 	}
 
 	return nil
@@ -302,53 +567,33 @@ COPY . /var/task
 	return nil
 }
 
-func buildDockerImage(directory string, config Config, phase string, dockerfilePath *os.File) error {
-	imageTag := config.GetImageTag()
-	if phase == "build" {
-		imageTag = fmt.Sprintf("%s-build", config.GetImageTag())
-	}
-
-	args := []string{
-		"image",
-		"build",
-		"--file", dockerfilePath.Name(),
-		"--progress", "plain",
-		"--tag", imageTag,
-	}
-
+func buildDockerImage(ctx context.Context, engine ContainerEngine, directory string, config Config, phase string, dockerfilePath *os.File) error {
+	outputPhase := PhaseBuild
 	if phase == "run" {
-		for _, label := range config.ImageLabels {
-			args = append(args, "--label", label)
-		}
+		outputPhase = PhaseImage
 	}
 
-	args = append(args, directory)
-
-	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, os.Interrupt)
-	ctx, cancel := context.WithCancel(context.Background())
-	go func() {
-		<-signals
-		cancel()
-	}()
+	output, flush := newOutputWriter(config, outputPhase)
+	defer flush()
 
-	cmd := execute.ExecTask{
-		Args:        args,
-		Command:     "docker",
-		Cwd:         config.WorkingDirectory,
-		StreamStdio: !config.RunQuiet,
+	input := BuildImageInput{
+		BuildContext:   directory,
+		DockerfilePath: dockerfilePath.Name(),
+		Output:         output,
+		Platform:       config.GetPlatform(),
+		Tag:            config.GetImageTag(),
 	}
 
-	res, err := cmd.Execute(ctx)
-	if err != nil {
-		return fmt.Errorf("error building image: %w", err)
+	if phase == "build" {
+		input.Platform = config.GetBuildPlatform()
+		input.Tag = config.GetBuildImageTag()
 	}
 
-	if res.ExitCode != 0 {
-		return fmt.Errorf("error building image, exit code %d", res.ExitCode)
+	if phase == "run" {
+		input.Labels = append([]string{fmt.Sprintf("com.dokku.lambda-builder/architecture=%s", config.GetArchitecture())}, config.ImageLabels...)
 	}
 
-	return nil
+	return engine.BuildImage(ctx, input)
 }
 
 func ParseLambdaYML(config Config) (LambdaYML, error) {
@@ -408,3 +653,63 @@ func getRunImage(config Config, defaultImage string) (string, error) {
 
 	return lambdaYML.RunImage, nil
 }
+
+// getIgnoreMatcher returns a matcher for the patterns in the .lambdaignore file,
+// falling back to the .dockerignore file, along with any lambda.yml excludes
+func getIgnoreMatcher(config Config) (*io.IgnoreMatcher, error) {
+	ignoreFile := filepath.Join(config.WorkingDirectory, ".lambdaignore")
+	if !io.FileExistsInDirectory(config.WorkingDirectory, ".lambdaignore") {
+		ignoreFile = filepath.Join(config.WorkingDirectory, ".dockerignore")
+	}
+
+	patterns, err := io.ReadIgnoreFile(ignoreFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", filepath.Base(ignoreFile), err)
+	}
+
+	lambdaYML, err := ParseLambdaYML(config)
+	if err != nil {
+		return nil, err
+	}
+
+	artifactPatterns, err := getArtifactIgnorePatterns(config)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns = append(patterns, lambdaYML.Exclude...)
+	patterns = append(patterns, artifactPatterns...)
+	return io.NewIgnoreMatcher(patterns)
+}
+
+func getCache(config Config) (bool, error) {
+	if config.Cache {
+		return true, nil
+	}
+
+	lambdaYML, err := ParseLambdaYML(config)
+	if err != nil {
+		return false, err
+	}
+
+	return lambdaYML.Cache, nil
+}
+
+// resolveDependencyFiles expands the dependency file patterns against
+// the build context, returning the sorted list of files that exist
+func resolveDependencyFiles(directory string, patterns []string) ([]string, error) {
+	files := []string{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(directory, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("error resolving dependency files: %w", err)
+		}
+
+		for _, match := range matches {
+			files = append(files, filepath.Base(match))
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
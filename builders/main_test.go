@@ -0,0 +1,188 @@
+package builders
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestZip returns a zip file containing the named files
+func newTestZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, contents := range files {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		header.SetMode(0755)
+		f, err := w.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := f.Write([]byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// newTestConfig returns a config building the app in a new working directory
+func newTestConfig(t *testing.T, engine ContainerEngine) Config {
+	t.Helper()
+
+	workingDirectory := filepath.Join(t.TempDir(), "app")
+	if err := os.MkdirAll(workingDirectory, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(workingDirectory, "bootstrap"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	return Config{
+		Builder:           "provided",
+		BuilderBuildImage: "mlupin/docker-lambda:provided.al2-build",
+		BuilderRunImage:   "mlupin/docker-lambda:provided.al2",
+		ContainerEngine:   engine,
+		HandlerMap:        map[string]string{"bootstrap": "bootstrap"},
+		Identifier:        "test",
+		RunQuiet:          true,
+		WorkingDirectory:  workingDirectory,
+	}
+}
+
+func TestExecuteBuilder(t *testing.T) {
+	engine := &FakeEngine{
+		Files: map[string][]byte{
+			"/var/task/lambda.zip": newTestZip(t, map[string]string{"bootstrap": "#!/bin/sh\n"}),
+		},
+	}
+
+	config := newTestConfig(t, engine)
+	if err := executeBuilder("#!/usr/bin/env bash", config); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.OpenReader(filepath.Join(config.WorkingDirectory, "lambda.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if len(r.File) != 1 || r.File[0].Name != "bootstrap" {
+		t.Fatalf("expected lambda.zip to contain only bootstrap, got %d entries", len(r.File))
+	}
+
+	if r.File[0].Mode().Perm()&0111 == 0 {
+		t.Errorf("expected bootstrap to be executable, got mode %s", r.File[0].Mode())
+	}
+
+	expected := []string{
+		"build lambda-builder/app:latest-build-test",
+		"copy lambda-builder/app:latest-build-test:/var/task/lambda.zip ",
+		"rmi lambda-builder/app:latest-build-test",
+	}
+
+	calls := []string{}
+	for _, call := range engine.Calls {
+		if !strings.HasPrefix(call, "inspect ") {
+			calls = append(calls, call)
+		}
+	}
+
+	if len(calls) != len(expected) {
+		t.Fatalf("expected calls %v, got %v", expected, engine.Calls)
+	}
+
+	for i := range expected {
+		if !strings.HasPrefix(calls[i], expected[i]) {
+			t.Fatalf("expected calls %v, got %v", expected, engine.Calls)
+		}
+	}
+}
+
+func TestExecuteBuilderBuildFailure(t *testing.T) {
+	engine := &FakeEngine{Err: errors.New("build failed")}
+
+	config := newTestConfig(t, engine)
+	err := executeBuilder("#!/usr/bin/env bash", config)
+	if err == nil {
+		t.Fatal("expected an error when the build image fails to build")
+	}
+
+	var buildErr *BuildError
+	if !errors.As(err, &buildErr) {
+		t.Fatalf("expected a BuildError, got %T", err)
+	}
+
+	if buildErr.Phase != PhaseBuild {
+		t.Errorf("expected the %s phase to fail, got %s", PhaseBuild, buildErr.Phase)
+	}
+
+	if len(engine.Calls) != 1 || engine.Calls[0] != "build lambda-builder/app:latest-build-test" {
+		t.Errorf("expected only the build image to be built, got %v", engine.Calls)
+	}
+
+	if _, err := os.Stat(filepath.Join(config.WorkingDirectory, "lambda.zip")); !os.IsNotExist(err) {
+		t.Error("expected no lambda.zip to be written")
+	}
+}
+
+func TestGetBuildImageTag(t *testing.T) {
+	config := Config{Identifier: "test", ImageTag: "lambda-builder/app:latest"}
+	if tag := config.GetBuildImageTag(); tag != "lambda-builder/app:latest-build-test" {
+		t.Errorf("expected a build image tag unique to the build, got %s", tag)
+	}
+
+	config.Cache = true
+	if tag := config.GetBuildImageTag(); tag != "lambda-builder/app:latest-build" {
+		t.Errorf("expected a stable build image tag when caching, got %s", tag)
+	}
+
+	config.Cache = false
+	config.KeepBuildImage = true
+	if tag := config.GetBuildImageTag(); tag != "lambda-builder/app:latest-build" {
+		t.Errorf("expected a stable build image tag when keeping the build image, got %s", tag)
+	}
+}
+
+func TestExecuteBuilderUnpublishedPlatform(t *testing.T) {
+	hostArchitecture, err := getHostArchitecture()
+	if err != nil {
+		t.Skip("unsupported host architecture")
+	}
+
+	architecture := ArchitectureArm64
+	if hostArchitecture == ArchitectureArm64 {
+		architecture = ArchitectureX86_64
+	}
+
+	engine := &FakeEngine{
+		Platforms: map[string][]string{
+			"mlupin/docker-lambda:provided.al2-build": {"linux/s390x"},
+		},
+	}
+
+	config := newTestConfig(t, engine)
+	config.Architecture = architecture
+	err = executeBuilder("#!/usr/bin/env bash", config)
+	if err == nil || !strings.Contains(err.Error(), "is not published for") {
+		t.Fatalf("expected an error for an image not published for the platform, got %v", err)
+	}
+
+	for _, call := range engine.Calls {
+		if strings.HasPrefix(call, "build ") {
+			t.Fatalf("expected the build to fail before building, got %v", engine.Calls)
+		}
+	}
+}
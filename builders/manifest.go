@@ -0,0 +1,231 @@
+package builders
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"lambda-builder/io"
+)
+
+// ManifestName is the name of the build manifest written alongside an artifact
+const ManifestName = "lambda-build.json"
+
+// BuildManifest describes the result of a build for consumption by deploy tooling
+type BuildManifest struct {
+	// Architecture is the lambda architecture the artifacts were built for
+	Architecture string `json:"architecture"`
+
+	// Artifacts lists each zip file produced by the build
+	Artifacts []ManifestArtifact `json:"artifacts"`
+
+	// BuildImage is the image the app was built within
+	BuildImage ManifestImage `json:"build_image"`
+
+	// Builder is the name of the builder used
+	Builder string `json:"builder"`
+
+	// DurationSeconds is the time taken to build the artifacts
+	DurationSeconds float64 `json:"duration_seconds"`
+
+	// Handler is the detected or specified function handler
+	Handler string `json:"handler,omitempty"`
+
+	// Image is the run image built from the artifact, if any
+	Image *ManifestImage `json:"image,omitempty"`
+
+	// LambdaBuilderVersion is the version of lambda-builder that produced the build
+	LambdaBuilderVersion string `json:"lambda_builder_version"`
+
+	// RunImage is the image the function is expected to run within
+	RunImage ManifestImage `json:"run_image"`
+
+	// Runtime is the lambda runtime derived from the run image
+	Runtime string `json:"runtime,omitempty"`
+}
+
+// ManifestArtifact describes a single zip file produced by a build
+type ManifestArtifact struct {
+	// FileCount is the number of files within the zip file
+	FileCount int `json:"file_count"`
+
+	// Name is the default name of the artifact, such as lambda.zip
+	Name string `json:"name"`
+
+	// Path is the absolute path the artifact was written to
+	Path string `json:"path"`
+
+	// SHA256 is the hex encoded SHA-256 digest of the zip file
+	SHA256 string `json:"sha256"`
+
+	// Size is the size of the zip file in bytes
+	Size int64 `json:"size"`
+}
+
+// ManifestImage identifies an image used or produced by a build
+type ManifestImage struct {
+	// Digest is the repository digest of the image, if known
+	Digest string `json:"digest,omitempty"`
+
+	// ID is the content-addressable ID of the local image, if present
+	ID string `json:"id,omitempty"`
+
+	// Name is the image reference
+	Name string `json:"name"`
+}
+
+// getManifestPath returns the path of the manifest for the build
+func getManifestPath(config Config, artifactPaths map[string]string) string {
+	return getManifestPathForArtifact(config, artifactPaths[getManifestArtifact(config)])
+}
+
+// getManifestArtifact returns the artifact the manifest is written alongside
+func getManifestArtifact(config Config) string {
+	if config.HasFunctionArtifact() {
+		return "lambda.zip"
+	}
+
+	return "layer.zip"
+}
+
+// getManifestPathForArtifact returns the manifest path for an artifact path. The
+// manifest is named after the artifact when it does not use its default name,
+// so that several builds may write to the same directory
+func getManifestPathForArtifact(config Config, path string) string {
+	name := filepath.Base(path)
+	if name == getManifestArtifact(config) {
+		return filepath.Join(filepath.Dir(path), ManifestName)
+	}
+
+	return filepath.Join(filepath.Dir(path), fmt.Sprintf("%s.%s", strings.TrimSuffix(name, filepath.Ext(name)), ManifestName))
+}
+
+// newBuildManifest gathers the details of a completed build
+func newBuildManifest(ctx context.Context, engine ContainerEngine, config Config, artifactPaths map[string]string, handler string, duration time.Duration) (BuildManifest, error) {
+	manifest := BuildManifest{
+		Architecture:         config.GetArchitecture(),
+		Artifacts:            []ManifestArtifact{},
+		BuildImage:           inspectManifestImage(ctx, engine, config.BuilderBuildImage),
+		Builder:              config.Builder,
+		DurationSeconds:      duration.Round(time.Millisecond).Seconds(),
+		Handler:              handler,
+		LambdaBuilderVersion: os.Getenv("CLI_VERSION"),
+		RunImage:             inspectManifestImage(ctx, engine, config.BuilderRunImage),
+		Runtime:              getRuntime(config.BuilderRunImage),
+	}
+
+	if config.GenerateRunImage {
+		image := inspectManifestImage(ctx, engine, config.GetImageTag())
+		manifest.Image = &image
+	}
+
+	for _, artifact := range config.GetArtifacts() {
+		path := artifactPaths[artifact]
+		size, err := io.FileSize(path)
+		if err != nil {
+			return manifest, fmt.Errorf("error getting filesize for %s: %w", artifact, err)
+		}
+
+		sha256, err := io.FileSHA256(path)
+		if err != nil {
+			return manifest, fmt.Errorf("error computing checksum for %s: %w", artifact, err)
+		}
+
+		fileCount, err := io.ZipFileCount(path)
+		if err != nil {
+			return manifest, fmt.Errorf("error reading %s: %w", artifact, err)
+		}
+
+		manifest.Artifacts = append(manifest.Artifacts, ManifestArtifact{
+			FileCount: fileCount,
+			Name:      artifact,
+			Path:      path,
+			SHA256:    sha256,
+			Size:      size,
+		})
+	}
+
+	return manifest, nil
+}
+
+// writeBuildManifest writes the manifest to path, replacing any existing manifest
+func writeBuildManifest(manifest BuildManifest, path string) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding build manifest: %w", err)
+	}
+
+	temporaryPath := fmt.Sprintf("%s.tmp", path)
+	if err := os.WriteFile(temporaryPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing build manifest: %w", err)
+	}
+
+	if err := os.Rename(temporaryPath, path); err != nil {
+		os.Remove(temporaryPath)
+		return fmt.Errorf("error writing build manifest: %w", err)
+	}
+
+	return nil
+}
+
+// inspectManifestImage resolves the identifiers of an image. Images that are
+// not present locally - such as base images pulled by buildkit - are recorded
+// by name only
+func inspectManifestImage(ctx context.Context, engine ContainerEngine, image string) ManifestImage {
+	manifestImage := ManifestImage{Name: image}
+	if image == "" {
+		return manifestImage
+	}
+
+	inspect, err := engine.InspectImage(ctx, image)
+	if err != nil {
+		return manifestImage
+	}
+
+	manifestImage.ID = inspect.ID
+	repository := getImageRepository(image)
+	for _, repoDigest := range inspect.RepoDigests {
+		parts := strings.SplitN(repoDigest, "@", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		if manifestImage.Digest == "" || parts[0] == repository {
+			manifestImage.Digest = parts[1]
+		}
+	}
+
+	return manifestImage
+}
+
+// getImageRepository strips the tag and digest from an image reference
+func getImageRepository(image string) string {
+	image = strings.SplitN(image, "@", 2)[0]
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+
+	return image
+}
+
+// getRuntime derives the lambda runtime from the run image reference, such as
+// python3.9 from mlupin/docker-lambda:python3.9 or from public.ecr.aws/lambda/python:3.9
+func getRuntime(runImage string) string {
+	image := strings.SplitN(runImage, "@", 2)[0]
+	repository := getImageRepository(image)
+	tag := strings.TrimPrefix(strings.TrimPrefix(image, repository), ":")
+	if tag == "" || tag == "latest" {
+		return ""
+	}
+
+	if unicode.IsLetter(rune(tag[0])) {
+		return tag
+	}
+
+	return filepath.Base(repository) + tag
+}
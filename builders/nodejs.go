@@ -1,6 +1,15 @@
 package builders
 
-import "lambda-builder/io"
+import (
+	"regexp"
+	"strconv"
+
+	"lambda-builder/io"
+)
+
+// nodejsRunImageVersion matches the major nodejs version within a run image,
+// such as mlupin/docker-lambda:nodejs14.x or public.ecr.aws/lambda/nodejs:18
+var nodejsRunImageVersion = regexp.MustCompile(`nodejs:?(\d+)`)
 
 type NodejsBuilder struct {
 	Config Config
@@ -32,7 +41,10 @@ func (b NodejsBuilder) Detect() bool {
 }
 
 func (b NodejsBuilder) Execute() error {
+	b.Config.Builder = b.Name()
+	b.Config.DependencyFiles = b.GetDependencyFiles()
 	b.Config.HandlerMap = b.GetHandlerMap()
+	b.Config.SlimPatterns = b.GetSlimPatterns()
 	return executeBuilder(b.script(), b.Config)
 }
 
@@ -44,6 +56,13 @@ func (b NodejsBuilder) GetConfig() Config {
 	return b.Config
 }
 
+func (b NodejsBuilder) GetDependencyFiles() []string {
+	return []string{
+		"package.json",
+		"package-lock.json",
+	}
+}
+
 func (b NodejsBuilder) GetHandlerMap() map[string]string {
 	return map[string]string{
 		"function.js":        "function.handler",
@@ -51,10 +70,47 @@ func (b NodejsBuilder) GetHandlerMap() map[string]string {
 	}
 }
 
+func (b NodejsBuilder) GetSlimPatterns() []string {
+	patterns := []string{
+		"**/node_modules/**/*.d.ts",
+		"**/node_modules/**/*.map",
+		"**/node_modules/**/*.md",
+		"**/node_modules/**/__tests__",
+		"**/node_modules/**/docs",
+		"**/node_modules/**/test",
+		"**/node_modules/**/tests",
+		"**/node_modules/.cache",
+		"**/node_modules/@types",
+	}
+
+	// the v2 sdk is only provided by nodejs 16 and older runtimes
+	if b.providesAwsSdkV2() {
+		patterns = append(patterns, "**/node_modules/aws-sdk")
+	}
+
+	return patterns
+}
+
+// providesAwsSdkV2 returns whether the run image is a nodejs runtime that provides
+// the aws-sdk package, which is assumed not to be the case for unknown images
+func (b NodejsBuilder) providesAwsSdkV2() bool {
+	match := nodejsRunImageVersion.FindStringSubmatch(b.Config.BuilderRunImage)
+	if match == nil {
+		return false
+	}
+
+	version, err := strconv.Atoi(match[1])
+	return err == nil && version <= 16
+}
+
 func (b NodejsBuilder) Name() string {
 	return "nodejs"
 }
 
+func (b NodejsBuilder) Shell() error {
+	return executeShell(b.script(), b.Config)
+}
+
 func (b NodejsBuilder) script() string {
 	return `
 #!/usr/bin/env bash
@@ -71,8 +127,22 @@ puts-step() {
 }
 
 install-npm() {
-  puts-step "Installing dependencies via npm"
-  npm install 2>&1 | indent
+  if [[ -f /tmp/lambda-builder-cached ]]; then
+    puts-step "Using cached dependencies"
+    return
+  fi
+
+  if [[ "$LAMBDA_BUILD_SLIM" == "1" ]]; then
+    puts-step "Installing production dependencies via npm"
+    npm install --production 2>&1 | indent
+  else
+    puts-step "Installing dependencies via npm"
+    npm install 2>&1 | indent
+  fi
+
+  if [[ "$LAMBDA_BUILD_PHASE" == "dependencies" ]]; then
+    touch /tmp/lambda-builder-cached
+  fi
 }
 
 hook-pre-compile() {
@@ -95,15 +165,40 @@ hook-post-compile() {
   bin/post_compile
 }
 
+package-layer() {
+  puts-step "Creating layer at layer.zip"
+  mkdir -p /tmp/layer/nodejs
+  cp -a /var/task/node_modules /tmp/layer/nodejs/
+  pushd /tmp/layer >/dev/null || return 1
+  zip -q -r /var/task/layer.zip nodejs
+  popd >/dev/null || return 1
+}
+
 hook-package() {
   if [[ "$LAMBDA_BUILD_ZIP" != "1" ]]; then
     return
   fi
 
+  if [[ "$LAMBDA_BUILD_LAYER" == "1" ]]; then
+    package-layer
+    if [[ "$LAMBDA_BUILD_LAYER_FUNCTION" != "1" ]]; then
+      return
+    fi
+
+    puts-step "Creating package at lambda.zip"
+    zip -q -r lambda.zip ./* -x layer.zip -x "node_modules/*"
+    return
+  fi
+
   puts-step "Creating package at lambda.zip"
   zip -q -r lambda.zip ./*
 }
 
+if [[ "$LAMBDA_BUILD_PHASE" == "dependencies" ]]; then
+  install-npm
+  exit 0
+fi
+
 hook-pre-compile
 install-npm
 hook-post-compile
@@ -0,0 +1,32 @@
+package builders
+
+import "testing"
+
+func TestNodejsSlimPatternsAwsSdk(t *testing.T) {
+	tests := []struct {
+		runImage string
+		expected bool
+	}{
+		{runImage: "mlupin/docker-lambda:nodejs14.x", expected: true},
+		{runImage: "mlupin/docker-lambda:nodejs16.x", expected: true},
+		{runImage: "public.ecr.aws/lambda/nodejs:18", expected: false},
+		{runImage: "mlupin/docker-lambda:nodejs20.x", expected: false},
+		{runImage: "example/custom:latest", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.runImage, func(t *testing.T) {
+			b := NodejsBuilder{Config: Config{BuilderRunImage: tt.runImage}}
+			removed := false
+			for _, pattern := range b.GetSlimPatterns() {
+				if pattern == "**/node_modules/aws-sdk" {
+					removed = true
+				}
+			}
+
+			if removed != tt.expected {
+				t.Errorf("expected aws-sdk removal to be %t for %s, got %t", tt.expected, tt.runImage, removed)
+			}
+		})
+	}
+}
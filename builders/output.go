@@ -0,0 +1,158 @@
+package builders
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// outputPlaceholder matches placeholders such as {{name}} in an output path
+var outputPlaceholder = regexp.MustCompile(`\{\{\s*([a-z]+)\s*\}\}`)
+
+// GetArtifactPaths returns the path each artifact produced by the builder is written to
+func GetArtifactPaths(builder Builder) (map[string]string, error) {
+	config := builder.GetConfig()
+	config.Builder = builder.Name()
+	return getArtifactPaths(config)
+}
+
+func getArtifactPaths(config Config) (map[string]string, error) {
+	var err error
+	config.Architecture, err = getArchitecture(config)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{
+		"arch":    config.GetArchitecture(),
+		"builder": config.Builder,
+		"id":      config.Identifier,
+		"name":    filepath.Base(config.WorkingDirectory),
+		"version": config.GetImageVersion(),
+	}
+
+	paths := map[string]string{}
+	artifacts := map[string]string{}
+	for _, artifact := range config.GetArtifacts() {
+		outputTemplate, err := getOutputTemplate(config, artifact)
+		if err != nil {
+			return nil, err
+		}
+
+		path, err := renderOutputTemplate(outputTemplate, values)
+		if err != nil {
+			return nil, err
+		}
+
+		if existing, ok := artifacts[path]; ok {
+			return nil, fmt.Errorf("%s and %s cannot both be written to %s", existing, artifact, path)
+		}
+
+		artifacts[path] = artifact
+		paths[artifact] = path
+	}
+
+	return paths, nil
+}
+
+// getOutputTemplate returns the absolute, unrendered output path for an artifact.
+// Paths set by flag are relative to the current directory, while paths set in
+// lambda.yml are relative to the working directory
+func getOutputTemplate(config Config, artifact string) (string, error) {
+	lambdaYML, err := ParseLambdaYML(config)
+	if err != nil {
+		return "", err
+	}
+
+	flagOutput, ymlOutput := config.Output, lambdaYML.Output
+	if artifact == "layer.zip" {
+		flagOutput, ymlOutput = config.LayerOutput, lambdaYML.LayerOutput
+	}
+
+	output := filepath.Join(config.WorkingDirectory, artifact)
+	if flagOutput != "" {
+		output, err = filepath.Abs(flagOutput)
+		if err != nil {
+			return "", fmt.Errorf("error resolving output path: %w", err)
+		}
+	} else if ymlOutput != "" {
+		output = ymlOutput
+		if !filepath.IsAbs(output) {
+			output = filepath.Join(config.WorkingDirectory, output)
+		}
+	}
+
+	// an output naming a directory receives the artifact under its default name
+	rawOutput := flagOutput
+	if rawOutput == "" {
+		rawOutput = ymlOutput
+	}
+	if strings.HasSuffix(rawOutput, "/") || strings.HasSuffix(rawOutput, string(filepath.Separator)) {
+		return filepath.Join(output, artifact), nil
+	}
+	if info, err := os.Stat(output); err == nil && info.IsDir() {
+		return filepath.Join(output, artifact), nil
+	}
+
+	return output, nil
+}
+
+// renderOutputTemplate replaces each placeholder in the output path with its value
+func renderOutputTemplate(output string, values map[string]string) (string, error) {
+	var err error
+	rendered := outputPlaceholder.ReplaceAllStringFunc(output, func(placeholder string) string {
+		key := outputPlaceholder.FindStringSubmatch(placeholder)[1]
+		value, ok := values[key]
+		if !ok {
+			err = fmt.Errorf("unknown placeholder in output path: %s", placeholder)
+			return placeholder
+		}
+
+		return value
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if strings.Contains(rendered, "{{") {
+		return "", fmt.Errorf("invalid placeholder in output path: %s", output)
+	}
+
+	return rendered, nil
+}
+
+// getArtifactIgnorePatterns returns ignore patterns matching any artifact or manifest
+// written within the working directory - including the temporary files written
+// while a build is in progress - so previous builds are not copied into the build context
+func getArtifactIgnorePatterns(config Config) ([]string, error) {
+	patterns := []string{}
+	for _, artifact := range config.GetArtifacts() {
+		outputTemplate, err := getOutputTemplate(config, artifact)
+		if err != nil {
+			return nil, err
+		}
+
+		relativePath, err := filepath.Rel(config.WorkingDirectory, outputTemplate)
+		if err != nil || relativePath == "." || strings.HasPrefix(relativePath, "..") {
+			continue
+		}
+
+		pattern := outputPlaceholder.ReplaceAllString(filepath.ToSlash(relativePath), "*")
+		patterns = append(patterns, pattern, getTemporaryArtifactPath(Config{Identifier: "*"}, pattern))
+		if artifact == getManifestArtifact(config) {
+			manifestPattern := getManifestPathForArtifact(config, pattern)
+			patterns = append(patterns, manifestPattern, fmt.Sprintf("%s.tmp", manifestPattern))
+		}
+	}
+
+	return patterns, nil
+}
+
+// getTemporaryArtifactPath returns the path an artifact is written to
+// before being moved into place once the build succeeds
+func getTemporaryArtifactPath(config Config, path string) string {
+	return filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.%s", filepath.Base(path), config.Identifier))
+}
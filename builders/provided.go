@@ -0,0 +1,162 @@
+package builders
+
+import (
+	"fmt"
+
+	"lambda-builder/io"
+)
+
+type ProvidedBuilder struct {
+	Config Config
+}
+
+func NewProvidedBuilder(config Config) (ProvidedBuilder, error) {
+	var err error
+	config.BuilderBuildImage, err = getBuildImage(config, "mlupin/docker-lambda:provided.al2-build")
+	if err != nil {
+		return ProvidedBuilder{}, err
+	}
+
+	config.BuilderRunImage, err = getRunImage(config, "mlupin/docker-lambda:provided.al2")
+	if err != nil {
+		return ProvidedBuilder{}, err
+	}
+
+	return ProvidedBuilder{
+		Config: config,
+	}, nil
+}
+
+func (b ProvidedBuilder) Detect() bool {
+	if io.ExecutableExistsInDirectory(b.Config.WorkingDirectory, "bootstrap") {
+		return true
+	}
+
+	lambdaYML, err := ParseLambdaYML(b.Config)
+	if err != nil {
+		return false
+	}
+
+	if lambdaYML.Runtime == "provided" || lambdaYML.Runtime == "provided.al2" {
+		return true
+	}
+
+	return false
+}
+
+func (b ProvidedBuilder) Execute() error {
+	if b.Config.Layer {
+		return fmt.Errorf("the %s builder does not support layer builds", b.Name())
+	}
+
+	b.Config.Builder = b.Name()
+	b.Config.DependencyFiles = b.GetDependencyFiles()
+	b.Config.HandlerMap = b.GetHandlerMap()
+	b.Config.SlimPatterns = b.GetSlimPatterns()
+	return executeBuilder(b.script(), b.Config)
+}
+
+func (b ProvidedBuilder) GetBuildImage() string {
+	return b.Config.BuilderBuildImage
+}
+
+func (b ProvidedBuilder) GetConfig() Config {
+	return b.Config
+}
+
+func (b ProvidedBuilder) GetDependencyFiles() []string {
+	return []string{}
+}
+
+func (b ProvidedBuilder) GetHandlerMap() map[string]string {
+	return map[string]string{
+		"bootstrap": "bootstrap",
+	}
+}
+
+func (b ProvidedBuilder) GetSlimPatterns() []string {
+	return []string{}
+}
+
+func (b ProvidedBuilder) Name() string {
+	return "provided"
+}
+
+func (b ProvidedBuilder) Shell() error {
+	return executeShell(b.script(), b.Config)
+}
+
+func (b ProvidedBuilder) script() string {
+	return `
+#!/usr/bin/env bash
+set -eo pipefail
+
+[ "$BUILDER_XTRACE" ] && set -o xtrace
+
+indent() {
+  sed -u "s/^/       /"
+}
+
+puts-step() {
+  echo "-----> $*"
+}
+
+puts-warning() {
+  echo " !     $*"
+}
+
+hook-compile() {
+  if [[ ! -f bin/compile ]]; then
+    return
+  fi
+
+  puts-step "Running compile hook"
+  chmod +x bin/compile
+  bin/compile 2>&1 | indent
+}
+
+hook-pre-compile() {
+  if [[ ! -f bin/pre_compile ]]; then
+    return
+  fi
+
+  puts-step "Running pre-compile hook"
+  chmod +x bin/pre_compile
+  bin/pre_compile
+}
+
+hook-post-compile() {
+  if [[ ! -f bin/post_compile ]]; then
+    return
+  fi
+
+  puts-step "Running post-compile hook"
+  chmod +x bin/post_compile
+  bin/post_compile
+}
+
+check-bootstrap() {
+  if [[ ! -f bootstrap ]]; then
+    puts-warning "No bootstrap file found in the app"
+    exit 1
+  fi
+
+  chmod +x bootstrap
+}
+
+hook-package() {
+  if [[ "$LAMBDA_BUILD_ZIP" != "1" ]]; then
+    return
+  fi
+
+  puts-step "Creating package at lambda.zip"
+  zip -q -r lambda.zip ./*
+}
+
+hook-pre-compile
+hook-compile
+hook-post-compile
+check-bootstrap
+hook-package
+`
+}
@@ -60,7 +60,10 @@ func (b PythonBuilder) Detect() bool {
 }
 
 func (b PythonBuilder) Execute() error {
+	b.Config.Builder = b.Name()
+	b.Config.DependencyFiles = b.GetDependencyFiles()
 	b.Config.HandlerMap = b.GetHandlerMap()
+	b.Config.SlimPatterns = b.GetSlimPatterns()
 	return executeBuilder(b.script(), b.Config)
 }
 
@@ -72,6 +75,16 @@ func (b PythonBuilder) GetConfig() Config {
 	return b.Config
 }
 
+func (b PythonBuilder) GetDependencyFiles() []string {
+	return []string{
+		"Pipfile",
+		"Pipfile.lock",
+		"poetry.lock",
+		"pyproject.toml",
+		"requirements.txt",
+	}
+}
+
 func (b PythonBuilder) GetHandlerMap() map[string]string {
 	return map[string]string{
 		"app.py":             "app.handler",
@@ -81,10 +94,34 @@ func (b PythonBuilder) GetHandlerMap() map[string]string {
 	}
 }
 
+func (b PythonBuilder) GetSlimPatterns() []string {
+	return []string{
+		"**/*.dist-info",
+		"**/*.egg-info",
+		"**/*.md",
+		"**/*.pyc",
+		"**/*.pyi",
+		"**/*.pyo",
+		"**/*.rst",
+		"**/__pycache__",
+		"**/docs",
+		"**/test",
+		"**/tests",
+		// provided by the python runtime
+		"**/boto3",
+		"**/botocore",
+		"**/s3transfer",
+	}
+}
+
 func (b PythonBuilder) Name() string {
 	return "python"
 }
 
+func (b PythonBuilder) Shell() error {
+	return executeShell(b.script(), b.Config)
+}
+
 func (b PythonBuilder) script() string {
 	return `
 #!/usr/bin/env bash
@@ -131,7 +168,11 @@ install-poetry() {
   puts-step "Installing dependencies via poetry"
   poetry config virtualenvs.create true
   poetry config virtualenvs.in-project true
-  poetry install --no-dev 2>&1 | indent
+  if [[ "$LAMBDA_BUILD_PHASE" == "dependencies" ]]; then
+    poetry install --no-dev --no-root 2>&1 | indent
+  else
+    poetry install --no-dev 2>&1 | indent
+  fi
 }
 
 python-major-minor() {
@@ -170,29 +211,64 @@ hook-post-compile() {
   bin/post_compile
 }
 
+package-layer() {
+  puts-step "Creating layer at layer.zip"
+  version="$(python-major-minor)"
+  mkdir -p "/tmp/layer/python/lib/python${version}"
+  cp -a "/var/task/.venv/lib/python${version}/site-packages" "/tmp/layer/python/lib/python${version}/"
+  pushd /tmp/layer >/dev/null || return 1
+  zip -q -r /var/task/layer.zip python
+  popd >/dev/null || return 1
+}
+
 hook-package() {
   if [[ "$LAMBDA_BUILD_ZIP" != "1" ]]; then
     return
   fi
 
+  if [[ "$LAMBDA_BUILD_LAYER" == "1" ]]; then
+    package-layer
+    if [[ "$LAMBDA_BUILD_LAYER_FUNCTION" != "1" ]]; then
+      return
+    fi
+  fi
+
   puts-step "Creating package at lambda.zip"
-  zip -q -r lambda.zip ./*
+  zip -q -r lambda.zip ./* -x layer.zip
 }
 
-hook-pre-compile
+install-dependencies() {
+  if [[ -f /tmp/lambda-builder-cached ]]; then
+    puts-step "Using cached dependencies"
+    return
+  fi
 
-if [[ -f "requirements.txt" ]]; then
-  install-pip
-elif [[ -f "Pipfile" ]]; then
-  install-pipenv
-elif [[ -f "poetry.lock" ]] || [[ -f "pyproject.toml" ]]; then
-  install-poetry
-else
-	puts-warning "No dependency file detected"
-	exit 1
+  if [[ -f "requirements.txt" ]]; then
+    install-pip
+  elif [[ -f "Pipfile" ]]; then
+    install-pipenv
+  elif [[ -f "poetry.lock" ]] || [[ -f "pyproject.toml" ]]; then
+    install-poetry
+  else
+    puts-warning "No dependency file detected"
+    exit 1
+  fi
+
+  if [[ "$LAMBDA_BUILD_PHASE" == "dependencies" ]]; then
+    touch /tmp/lambda-builder-cached
+  fi
+}
+
+if [[ "$LAMBDA_BUILD_PHASE" == "dependencies" ]]; then
+  install-dependencies
+  exit 0
 fi
 
-cleanup-deps
+hook-pre-compile
+install-dependencies
+if [[ "$LAMBDA_BUILD_LAYER" != "1" ]]; then
+  cleanup-deps
+fi
 hook-post-compile
 hook-package
 `
@@ -0,0 +1,139 @@
+package builders
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// BuilderFactory constructs a builder for the given config
+type BuilderFactory func(config Config) (Builder, error)
+
+// registeredBuilder is a named builder factory in the registry
+type registeredBuilder struct {
+	factory BuilderFactory
+	name    string
+}
+
+var (
+	// registry holds the registered builders in detection order
+	registry []registeredBuilder
+
+	// registryDefinedCount is the number of builders at the head of the
+	// registry that were loaded from builder definition files
+	registryDefinedCount int
+
+	registryMu sync.RWMutex
+)
+
+func init() {
+	RegisterBuilder("dotnet", func(config Config) (Builder, error) { return NewDotnetBuilder(config) })
+	RegisterBuilder("go", func(config Config) (Builder, error) { return NewGoBuilder(config) })
+	RegisterBuilder("java", func(config Config) (Builder, error) { return NewJavaBuilder(config) })
+	RegisterBuilder("nodejs", func(config Config) (Builder, error) { return NewNodejsBuilder(config) })
+	RegisterBuilder("python", func(config Config) (Builder, error) { return NewPythonBuilder(config) })
+	RegisterBuilder("ruby", func(config Config) (Builder, error) { return NewRubyBuilder(config) })
+	RegisterBuilder("rust", func(config Config) (Builder, error) { return NewRustBuilder(config) })
+
+	// detected last, as other builders may also produce a bootstrap file
+	RegisterBuilder("provided", func(config Config) (Builder, error) { return NewProvidedBuilder(config) })
+}
+
+// RegisterBuilder adds a builder to the registry. Builders are detected in
+// the order they are registered, and registering an existing name panics
+func RegisterBuilder(name string, factory BuilderFactory) {
+	if err := registerBuilder(name, factory, false); err != nil {
+		panic(err)
+	}
+}
+
+// registerBuilder adds a builder to the registry. Builders loaded from
+// definition files are placed ahead of the built-in builders, in load order
+func registerBuilder(name string, factory BuilderFactory, defined bool) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, b := range registry {
+		if b.name == name {
+			return fmt.Errorf("builder %s is already registered", name)
+		}
+	}
+
+	entry := registeredBuilder{factory: factory, name: name}
+	if !defined {
+		registry = append(registry, entry)
+		return nil
+	}
+
+	i := registryDefinedCount
+	registry = append(registry[:i], append([]registeredBuilder{entry}, registry[i:]...)...)
+	registryDefinedCount++
+	return nil
+}
+
+// BuilderNames returns the sorted names of the registered builders
+func BuilderNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := []string{}
+	for _, b := range registry {
+		names = append(names, b.name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// DetectBuilder returns the first builder that detects the app in the working
+// directory, limited to the builder selected via the config or lambda.yml.
+// A provided runtime in lambda.yml selects the provided builder. Builders
+// defined in lambda.yml are detected ahead of the registered builders
+func DetectBuilder(config Config) (Builder, error) {
+	lambdaYML, err := ParseLambdaYML(config)
+	if err != nil {
+		return nil, err
+	}
+
+	factories := []registeredBuilder{}
+	for _, definition := range lambdaYML.Builders {
+		if err := definition.load(config.WorkingDirectory); err != nil {
+			return nil, fmt.Errorf("error loading builder from lambda.yml: %w", err)
+		}
+
+		factories = append(factories, registeredBuilder{factory: definition.factory(), name: definition.Name})
+	}
+
+	registryMu.RLock()
+	factories = append(factories, registry...)
+	registryMu.RUnlock()
+
+	selectedImage := lambdaYML.Builder
+	if config.Builder != "" {
+		selectedImage = config.Builder
+	}
+
+	// a provided runtime is built as is, rather than by a builder that
+	// happens to detect the app ahead of the provided builder
+	if selectedImage == "" && (lambdaYML.Runtime == "provided" || lambdaYML.Runtime == "provided.al2") {
+		selectedImage = "provided"
+	}
+
+	for _, b := range factories {
+		if selectedImage != "" && selectedImage != b.name {
+			continue
+		}
+
+		builder, err := b.factory(config)
+		if err != nil {
+			return nil, err
+		}
+
+		if builder.Detect() {
+			return builder, nil
+		}
+	}
+
+	return nil, errors.New("no builder detected")
+}
@@ -39,6 +39,13 @@ func (b RubyBuilder) GetConfig() Config {
 	return b.Config
 }
 
+func (b RubyBuilder) GetDependencyFiles() []string {
+	return []string{
+		"Gemfile",
+		"Gemfile.lock",
+	}
+}
+
 func (b RubyBuilder) GetHandlerMap() map[string]string {
 	return map[string]string{
 		"function.rb":        "function.handler",
@@ -46,8 +53,21 @@ func (b RubyBuilder) GetHandlerMap() map[string]string {
 	}
 }
 
+func (b RubyBuilder) GetSlimPatterns() []string {
+	return []string{
+		"**/gems/*/doc",
+		"**/gems/*/spec",
+		"**/gems/*/test",
+		"ruby/gems/*/cache",
+		"vendor/bundle/ruby/*/cache",
+	}
+}
+
 func (b RubyBuilder) Execute() error {
+	b.Config.Builder = b.Name()
+	b.Config.DependencyFiles = b.GetDependencyFiles()
 	b.Config.HandlerMap = b.GetHandlerMap()
+	b.Config.SlimPatterns = b.GetSlimPatterns()
 	return executeBuilder(b.script(), b.Config)
 }
 
@@ -55,6 +75,10 @@ func (b RubyBuilder) Name() string {
 	return "ruby"
 }
 
+func (b RubyBuilder) Shell() error {
+	return executeShell(b.script(), b.Config)
+}
+
 func (b RubyBuilder) script() string {
 	return `
 #!/usr/bin/env bash
@@ -71,9 +95,18 @@ puts-step() {
 }
 
 install-bundler() {
+  if [[ -f /tmp/lambda-builder-cached ]]; then
+    puts-step "Using cached dependencies"
+    return
+  fi
+
   puts-step "Downloading dependencies via bundler"
   bundle config set --local path 'vendor/bundle' 2>&1 | indent
   bundle install 2>&1 | indent
+
+  if [[ "$LAMBDA_BUILD_PHASE" == "dependencies" ]]; then
+    touch /tmp/lambda-builder-cached
+  fi
 }
 
 hook-pre-compile() {
@@ -96,15 +129,40 @@ hook-post-compile() {
   bin/post_compile
 }
 
+package-layer() {
+  puts-step "Creating layer at layer.zip"
+  mkdir -p /tmp/layer/ruby/gems
+  cp -a /var/task/vendor/bundle/ruby/. /tmp/layer/ruby/gems/
+  pushd /tmp/layer >/dev/null || return 1
+  zip -q -r /var/task/layer.zip ruby
+  popd >/dev/null || return 1
+}
+
 hook-package() {
   if [[ "$LAMBDA_BUILD_ZIP" != "1" ]]; then
     return
   fi
 
+  if [[ "$LAMBDA_BUILD_LAYER" == "1" ]]; then
+    package-layer
+    if [[ "$LAMBDA_BUILD_LAYER_FUNCTION" != "1" ]]; then
+      return
+    fi
+
+    puts-step "Creating package at lambda.zip"
+    zip -q -r lambda.zip ./* -x layer.zip -x "vendor/bundle/*"
+    return
+  fi
+
   puts-step "Creating package at lambda.zip"
   zip -q -r lambda.zip ./*
 }
 
+if [[ "$LAMBDA_BUILD_PHASE" == "dependencies" ]]; then
+  install-bundler
+  exit 0
+fi
+
 hook-pre-compile
 install-bundler
 hook-post-compile
@@ -0,0 +1,361 @@
+package builders
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"lambda-builder/io"
+
+	"github.com/BurntSushi/toml"
+)
+
+type RustBuilder struct {
+	Config Config
+}
+
+func NewRustBuilder(config Config) (RustBuilder, error) {
+	libc, err := getRustLibc(config)
+	if err != nil {
+		return RustBuilder{}, err
+	}
+
+	// glibc builds use the al2 build image so the binary links against the runtime's glibc
+	defaultBuilder := "rust:1-bookworm"
+	if libc == "gnu" {
+		defaultBuilder = "mlupin/docker-lambda:provided.al2-build"
+	}
+
+	config.BuilderBuildImage, err = getBuildImage(config, defaultBuilder)
+	if err != nil {
+		return RustBuilder{}, err
+	}
+
+	config.BuilderRunImage, err = getRunImage(config, "mlupin/docker-lambda:provided.al2")
+	if err != nil {
+		return RustBuilder{}, err
+	}
+
+	return RustBuilder{
+		Config: config,
+	}, nil
+}
+
+func (b RustBuilder) Detect() bool {
+	if io.FileExistsInDirectory(b.Config.WorkingDirectory, "Cargo.toml") {
+		return true
+	}
+
+	return false
+}
+
+func (b RustBuilder) Execute() error {
+	if b.Config.Layer {
+		return fmt.Errorf("the %s builder does not support layer builds", b.Name())
+	}
+
+	buildEnv, err := b.getBuildEnv()
+	if err != nil {
+		return err
+	}
+
+	b.Config.Builder = b.Name()
+	b.Config.BuildEnv = buildEnv
+	b.Config.DependencyFiles = b.GetDependencyFiles()
+	b.Config.HandlerMap = b.GetHandlerMap()
+	b.Config.SlimPatterns = b.GetSlimPatterns()
+	return executeBuilder(b.script(), b.Config)
+}
+
+func (b RustBuilder) GetBuildImage() string {
+	return b.Config.BuilderBuildImage
+}
+
+func (b RustBuilder) GetConfig() Config {
+	return b.Config
+}
+
+func (b RustBuilder) GetDependencyFiles() []string {
+	return []string{
+		"Cargo.lock",
+		"Cargo.toml",
+	}
+}
+
+func (b RustBuilder) GetHandlerMap() map[string]string {
+	return map[string]string{
+		"bootstrap": "bootstrap",
+	}
+}
+
+func (b RustBuilder) GetSlimPatterns() []string {
+	return []string{}
+}
+
+func (b RustBuilder) Name() string {
+	return "rust"
+}
+
+func (b RustBuilder) Shell() error {
+	buildEnv, err := b.getBuildEnv()
+	if err != nil {
+		return err
+	}
+
+	b.Config.BuildEnv = buildEnv
+	return executeShell(b.script(), b.Config)
+}
+
+// getBuildEnv returns the build env with the binary and libc the script builds for
+func (b RustBuilder) getBuildEnv() ([]string, error) {
+	libc, err := getRustLibc(b.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	binary, err := getRustBinary(b.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]string{
+		fmt.Sprintf("LAMBDA_RUST_BINARY=%s", binary),
+		fmt.Sprintf("LAMBDA_RUST_LIBC=%s", libc),
+	}, b.Config.BuildEnv...), nil
+}
+
+func (b RustBuilder) script() string {
+	return `
+#!/usr/bin/env bash
+set -eo pipefail
+
+[ "$BUILDER_XTRACE" ] && set -o xtrace
+
+indent() {
+  sed -u "s/^/       /"
+}
+
+puts-step() {
+  echo "-----> $*"
+}
+
+puts-warning() {
+  echo " !     $*"
+}
+
+rust-target() {
+  arch="x86_64"
+  if [[ "$LAMBDA_ARCHITECTURE" == "arm64" ]]; then
+    arch="aarch64"
+  fi
+
+  echo "${arch}-unknown-linux-${LAMBDA_RUST_LIBC}"
+}
+
+install-rust() {
+  if ! command -v cargo >/dev/null 2>&1; then
+    if [[ -f "$HOME/.cargo/env" ]]; then
+      source "$HOME/.cargo/env"
+    else
+      puts-step "Installing rust via rustup"
+      curl --proto "=https" --tlsv1.2 -sSf https://sh.rustup.rs | sh -s -- -y --profile minimal 2>&1 | indent
+      source "$HOME/.cargo/env"
+    fi
+  fi
+
+  if [[ "$LAMBDA_RUST_LIBC" == "musl" ]] && ! command -v musl-gcc >/dev/null 2>&1; then
+    puts-step "Installing musl-tools dependency for linking"
+    apt-get update 2>&1 | indent
+    apt-get install -y --no-install-recommends musl-tools 2>&1 | indent
+  fi
+
+  puts-step "Adding $(rust-target) target via rustup"
+  rustup target add "$(rust-target)" 2>&1 | indent
+}
+
+install-dependencies() {
+  # cargo requires a target to load the manifest
+  created_stub=false
+  if [[ ! -d src ]]; then
+    mkdir -p src
+    echo "fn main() {}" >src/main.rs
+    created_stub=true
+  fi
+
+  puts-step "Downloading dependencies via cargo fetch"
+  if ! cargo fetch 2>&1 | indent; then
+    puts-warning "Unable to download dependencies ahead of the build"
+  fi
+
+  if [[ "$created_stub" == "true" ]]; then
+    rm -rf src
+  fi
+}
+
+compile() {
+  locked=()
+  if [[ -f "Cargo.lock" ]]; then
+    locked=(--locked)
+  fi
+
+  puts-step "Compiling ${LAMBDA_RUST_BINARY} via cargo build for $(rust-target)"
+  cargo build --release "${locked[@]}" --target "$(rust-target)" --bin "$LAMBDA_RUST_BINARY" 2>&1 | indent
+  cp "target/$(rust-target)/release/${LAMBDA_RUST_BINARY}" bootstrap
+}
+
+hook-pre-compile() {
+  if [[ ! -f bin/pre_compile ]]; then
+    return
+  fi
+
+  puts-step "Running pre-compile hook"
+  chmod +x bin/pre_compile
+  bin/pre_compile
+}
+
+hook-post-compile() {
+  if [[ ! -f bin/post_compile ]]; then
+    return
+  fi
+
+  puts-step "Running post-compile hook"
+  chmod +x bin/post_compile
+  bin/post_compile
+}
+
+hook-package() {
+  if [[ "$LAMBDA_BUILD_ZIP" != "1" ]]; then
+    return
+  fi
+
+  if ! command -v zip >/dev/null 2>&1; then
+    puts-step "Installing zip dependency for packaging"
+    apt-get update && apt-get install -y --no-install-recommends zip
+  fi
+
+  puts-step "Creating package at lambda.zip"
+  zip -q -r lambda.zip bootstrap
+}
+
+install-rust
+
+if [[ "$LAMBDA_BUILD_PHASE" == "dependencies" ]]; then
+  install-dependencies
+  exit 0
+fi
+
+hook-pre-compile
+compile
+hook-post-compile
+hook-package
+`
+}
+
+// getRustLibc returns the libc to link rust binaries against, musl by default
+func getRustLibc(config Config) (string, error) {
+	lambdaYML, err := ParseLambdaYML(config)
+	if err != nil {
+		return "", err
+	}
+
+	switch lambdaYML.RustLibc {
+	case "", "musl":
+		return "musl", nil
+	case "gnu", "glibc":
+		return "gnu", nil
+	}
+
+	return "", fmt.Errorf("unsupported rust_libc '%s', expected musl or gnu", lambdaYML.RustLibc)
+}
+
+// getRustBinary returns the binary target to package as the bootstrap,
+// selected via the rust_binary key in lambda.yml when the crate has several
+func getRustBinary(config Config) (string, error) {
+	lambdaYML, err := ParseLambdaYML(config)
+	if err != nil {
+		return "", err
+	}
+
+	if lambdaYML.RustBinary != "" {
+		return lambdaYML.RustBinary, nil
+	}
+
+	binaries, err := parseRustBinaries(config.WorkingDirectory)
+	if err != nil {
+		return "", err
+	}
+
+	if len(binaries) == 1 {
+		return binaries[0], nil
+	}
+
+	for _, binary := range binaries {
+		if binary == "bootstrap" {
+			return binary, nil
+		}
+	}
+
+	if len(binaries) == 0 {
+		return "", fmt.Errorf("no binary targets found in Cargo.toml, select one via the rust_binary key in lambda.yml")
+	}
+
+	return "", fmt.Errorf("multiple binary targets found in Cargo.toml (%s), select one via the rust_binary key in lambda.yml", strings.Join(binaries, ", "))
+}
+
+// parseRustBinaries returns the sorted names of the binary targets declared
+// in Cargo.toml, along with those cargo discovers in src/main.rs and src/bin
+func parseRustBinaries(workingDirectory string) ([]string, error) {
+	bytes, err := os.ReadFile(filepath.Join(workingDirectory, "Cargo.toml"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading Cargo.toml: %w", err)
+	}
+
+	type CargoManifest struct {
+		Bin []struct {
+			Name string `toml:"name"`
+		} `toml:"bin"`
+		Package struct {
+			Autobins *bool  `toml:"autobins"`
+			Name     string `toml:"name"`
+		} `toml:"package"`
+	}
+	var manifest CargoManifest
+	if err := toml.Unmarshal(bytes, &manifest); err != nil {
+		return nil, fmt.Errorf("error unmarshaling Cargo.toml: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for _, bin := range manifest.Bin {
+		seen[bin.Name] = true
+	}
+
+	autobins := manifest.Package.Autobins == nil || *manifest.Package.Autobins
+	if autobins && manifest.Package.Name != "" {
+		if io.FileExistsInDirectory(workingDirectory, filepath.Join("src", "main.rs")) {
+			seen[manifest.Package.Name] = true
+		}
+
+		entries, err := os.ReadDir(filepath.Join(workingDirectory, "src", "bin"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("error reading src/bin: %w", err)
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".rs") {
+				seen[strings.TrimSuffix(entry.Name(), ".rs")] = true
+			} else if entry.IsDir() && io.FileExistsInDirectory(filepath.Join(workingDirectory, "src", "bin", entry.Name()), "main.rs") {
+				seen[entry.Name()] = true
+			}
+		}
+	}
+
+	binaries := []string{}
+	for binary := range seen {
+		binaries = append(binaries, binary)
+	}
+
+	sort.Strings(binaries)
+	return binaries, nil
+}
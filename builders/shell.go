@@ -0,0 +1,68 @@
+package builders
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// executeShell starts an interactive shell within the build image, with the
+// build script available at /usr/local/bin/build-lambda. Unless an existing
+// build image is reused, the working directory is mounted read-only and
+// copied to /var/task, so that the shell cannot modify the app.
+func executeShell(script string, config Config) error {
+	engine, err := getContainerEngine(config)
+	if err != nil {
+		return err
+	}
+
+	config.Architecture, err = getArchitecture(config)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	scriptPath, err := os.CreateTemp("", "lambda-builder")
+	if err != nil {
+		return fmt.Errorf("error generating temporary build script: %w", err)
+	}
+
+	defer func() {
+		os.Remove(scriptPath.Name())
+	}()
+
+	if _, err := scriptPath.WriteString(strings.TrimSpace(script)); err != nil {
+		return err
+	}
+
+	if err := scriptPath.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(scriptPath.Name(), 0755); err != nil {
+		return err
+	}
+
+	input := RunContainerInput{
+		Command:          []string{"/bin/bash"},
+		ContainerName:    fmt.Sprintf("lambda-builder-shell-%s", config.Identifier),
+		Env:              getBuildEnv(config),
+		Image:            config.BuilderBuildImage,
+		Labels:           []string{"com.dokku.lambda-builder/shell=true"},
+		Platform:         config.GetBuildPlatform(),
+		Volumes:          []string{fmt.Sprintf("%s:/usr/local/bin/build-lambda:ro", scriptPath.Name())},
+		WorkingDirectory: "/var/task",
+	}
+
+	if config.ReuseBuildImage {
+		input.Image = config.GetBuildImageTag()
+	} else {
+		input.Command = []string{"/bin/bash", "-c", "cp -a /tmp/lambda-builder-app/. /var/task && exec /bin/bash"}
+		input.Volumes = append(input.Volumes, fmt.Sprintf("%s:/tmp/lambda-builder-app:ro", config.WorkingDirectory))
+	}
+
+	config.GetLogger().LogHeader2(fmt.Sprintf("Starting shell in %s", input.Image))
+	return engine.RunContainer(ctx, input)
+}
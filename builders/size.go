@@ -0,0 +1,52 @@
+package builders
+
+const (
+	// DefaultMaxUnzippedSize is the AWS Lambda limit on the unzipped size
+	// of a function and its layers, in megabytes
+	DefaultMaxUnzippedSize = 250
+
+	// DefaultMaxZipSize is the AWS Lambda limit on the size of a zip file
+	// uploaded directly to a function, in megabytes
+	DefaultMaxZipSize = 50
+)
+
+// SizePolicy contains the limits artifacts are checked against
+type SizePolicy struct {
+	// FailOnSizeLimit fails the build when a limit is surpassed
+	FailOnSizeLimit bool
+
+	// MaxUnzippedSize is the maximum unzipped size of an artifact, in megabytes
+	MaxUnzippedSize int64
+
+	// MaxZipSize is the maximum size of an artifact, in megabytes
+	MaxZipSize int64
+}
+
+// GetSizePolicy returns the size policy for the build, with flags
+// taking precedence over lambda.yml, which takes precedence over the defaults
+func GetSizePolicy(config Config) (SizePolicy, error) {
+	lambdaYML, err := ParseLambdaYML(config)
+	if err != nil {
+		return SizePolicy{}, err
+	}
+
+	policy := SizePolicy{
+		FailOnSizeLimit: config.FailOnSizeLimit || lambdaYML.FailOnSizeLimit,
+		MaxUnzippedSize: DefaultMaxUnzippedSize,
+		MaxZipSize:      DefaultMaxZipSize,
+	}
+
+	if config.MaxUnzippedSize > 0 {
+		policy.MaxUnzippedSize = config.MaxUnzippedSize
+	} else if lambdaYML.MaxUnzippedSize > 0 {
+		policy.MaxUnzippedSize = lambdaYML.MaxUnzippedSize
+	}
+
+	if config.MaxZipSize > 0 {
+		policy.MaxZipSize = config.MaxZipSize
+	} else if lambdaYML.MaxZipSize > 0 {
+		policy.MaxZipSize = lambdaYML.MaxZipSize
+	}
+
+	return policy, nil
+}
@@ -0,0 +1,55 @@
+package builders
+
+import (
+	"fmt"
+
+	"lambda-builder/io"
+)
+
+// getSlim returns true if artifacts should be slimmed, as
+// specified by either the --slim flag or lambda.yml
+func getSlim(config Config) (bool, error) {
+	if config.Slim {
+		return true, nil
+	}
+
+	lambdaYML, err := ParseLambdaYML(config)
+	if err != nil {
+		return false, err
+	}
+
+	return lambdaYML.Slim, nil
+}
+
+// getSlimMatcher returns a matcher for the files removed from artifacts by the
+// builder, re-including any paths listed under slim_keep in lambda.yml
+func getSlimMatcher(config Config) (*io.IgnoreMatcher, error) {
+	lambdaYML, err := ParseLambdaYML(config)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := append([]string{}, config.SlimPatterns...)
+	for _, pattern := range lambdaYML.SlimKeep {
+		patterns = append(patterns, fmt.Sprintf("!%s", pattern))
+	}
+
+	return io.NewIgnoreMatcher(patterns)
+}
+
+// slimArtifact removes the files matched by the slim patterns
+// from the extracted contents of an artifact
+func slimArtifact(config Config, artifact string, directory string) error {
+	matcher, err := getSlimMatcher(config)
+	if err != nil {
+		return newBuildError(PhasePackage, err)
+	}
+
+	count, size, err := io.RemoveMatching(directory, matcher)
+	if err != nil {
+		return newBuildError(PhasePackage, fmt.Errorf("error slimming %s: %w", artifact, err))
+	}
+
+	config.GetLogger().Info(fmt.Sprintf("Slimmed %s: removed %d files (%dKB)", artifact, count, io.BytesToKilobytes(size)))
+	return nil
+}
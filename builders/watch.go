@@ -0,0 +1,23 @@
+package builders
+
+import (
+	"context"
+	"time"
+
+	"lambda-builder/io"
+)
+
+// watchInterval is how often the working directory is polled for changes
+const watchInterval = time.Second
+
+// WatchWorkingDirectory calls onChange whenever a file within the working
+// directory changes until the context is done. Files excluded from the build
+// context - including the artifacts written by a build - are not watched
+func WatchWorkingDirectory(ctx context.Context, config Config, onChange func()) error {
+	matcher, err := getIgnoreMatcher(config)
+	if err != nil {
+		return err
+	}
+
+	return io.WatchDirectory(ctx, config.WorkingDirectory, matcher, watchInterval, onChange)
+}
@@ -0,0 +1,297 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"lambda-builder/io"
+	"lambda-builder/ui"
+
+	"github.com/josegonzalez/cli-skeleton/command"
+	"github.com/posener/complete"
+	flag "github.com/spf13/pflag"
+)
+
+const (
+	// AnalyzeFormatJSON outputs the analysis as a json document
+	AnalyzeFormatJSON = "json"
+
+	// AnalyzeFormatText outputs the analysis as a human-readable report
+	AnalyzeFormatText = "text"
+)
+
+type AnalyzeCommand struct {
+	command.Meta
+
+	depth  int
+	flat   bool
+	format string
+	limit  int
+}
+
+func (c *AnalyzeCommand) Name() string {
+	return "analyze"
+}
+
+func (c *AnalyzeCommand) Synopsis() string {
+	return "Reports where the size of an artifact comes from"
+}
+
+func (c *AnalyzeCommand) Help() string {
+	return command.CommandHelp(c)
+}
+
+func (c *AnalyzeCommand) Examples() map[string]string {
+	appName := os.Getenv("CLI_APP_NAME")
+	return map[string]string{
+		"Analyzes the lambda.zip in the current directory": fmt.Sprintf("%s %s", appName, c.Name()),
+		"Lists the 20 largest files within an artifact":    fmt.Sprintf("%s %s --flat --limit 20 dist/lambda.zip", appName, c.Name()),
+		"Outputs the analysis as json":                     fmt.Sprintf("%s %s --format json", appName, c.Name()),
+	}
+}
+
+func (c *AnalyzeCommand) Arguments() []command.Argument {
+	args := []command.Argument{}
+	args = append(args, command.Argument{
+		Name:        "artifact",
+		Description: "path to the zip file to analyze, defaulting to lambda.zip",
+		Optional:    true,
+		Type:        command.ArgumentString,
+	})
+	return args
+}
+
+func (c *AnalyzeCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictFiles("*.zip")
+}
+
+func (c *AnalyzeCommand) ParsedArguments(args []string) (map[string]command.Argument, error) {
+	return command.ParseArguments(args, c.Arguments())
+}
+
+func (c *AnalyzeCommand) FlagSet() *flag.FlagSet {
+	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
+	f.BoolVar(&c.flat, "flat", false, "list the largest files rather than the directory tree")
+	f.IntVar(&c.depth, "depth", 3, "number of directory levels to output in the tree")
+	f.IntVar(&c.limit, "limit", 10, "number of entries to output in each section")
+	f.StringVar(&c.format, "format", AnalyzeFormatText, "output format (text or json)")
+	return f
+}
+
+func (c *AnalyzeCommand) AutocompleteFlags() complete.Flags {
+	return command.MergeAutocompleteFlags(
+		c.Meta.AutocompleteFlags(command.FlagSetClient),
+		complete.Flags{
+			"--depth":  complete.PredictAnything,
+			"--flat":   complete.PredictNothing,
+			"--format": complete.PredictSet(AnalyzeFormatJSON, AnalyzeFormatText),
+			"--limit":  complete.PredictAnything,
+		},
+	)
+}
+
+func (c *AnalyzeCommand) Run(args []string) int {
+	flags := c.FlagSet()
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		c.Ui.Error(err.Error())
+		c.Ui.Error(command.CommandErrorText(c))
+		return 1
+	}
+
+	arguments, err := c.ParsedArguments(flags.Args())
+	if err != nil {
+		c.Ui.Error(err.Error())
+		c.Ui.Error(command.CommandErrorText(c))
+		return 1
+	}
+
+	if c.format != AnalyzeFormatJSON && c.format != AnalyzeFormatText {
+		c.Ui.Error(fmt.Sprintf("Unsupported format: %s", c.format))
+		return 1
+	}
+
+	if c.depth < 1 || c.limit < 1 {
+		c.Ui.Error("The --depth and --limit flags must be at least 1")
+		return 1
+	}
+
+	logger, ok := c.Ui.(*ui.ZerologUi)
+	if !ok {
+		c.Ui.Error("Unable to fetch logger from cli")
+		return 1
+	}
+
+	path := "lambda.zip"
+	if arguments["artifact"].HasValue {
+		path = arguments["artifact"].StringValue()
+	}
+
+	analysis, err := io.AnalyzeZip(path)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error analyzing %s: %s", path, err.Error()))
+		return 1
+	}
+
+	if c.format == AnalyzeFormatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(analysis); err != nil {
+			logger.Error(fmt.Sprintf("Error encoding analysis: %s", err.Error()))
+			return 1
+		}
+
+		return 0
+	}
+
+	c.renderAnalysis(logger, analysis)
+	return 0
+}
+
+// renderAnalysis outputs a human-readable report of the analysis
+func (c *AnalyzeCommand) renderAnalysis(logger *ui.ZerologUi, analysis io.ZipAnalysis) {
+	logger.LogHeader1(fmt.Sprintf("Analyzing %s", analysis.Path))
+	logger.Info(fmt.Sprintf("Zip file size: %s", formatBytes(analysis.Size)))
+	logger.Info(fmt.Sprintf("Unzipped size: %s", formatBytes(analysis.UncompressedSize)))
+	logger.Info(fmt.Sprintf("Files: %d", analysis.FileCount))
+	logger.Info(fmt.Sprintf("Compression ratio: %.1fx", analysis.CompressionRatio))
+
+	if c.flat {
+		files := analysis.Files
+		if len(files) > c.limit {
+			files = files[:c.limit]
+		}
+
+		logger.LogHeader2("Largest files")
+		c.renderTable(logger, func(w *tabwriter.Writer) {
+			fmt.Fprintln(w, sizeHeader)
+			for _, file := range files {
+				writeSizeRow(w, file, file.Path)
+			}
+		})
+	} else {
+		logger.LogHeader2("Largest paths")
+		c.renderTable(logger, func(w *tabwriter.Writer) {
+			fmt.Fprintln(w, sizeHeader)
+			writeSizeRow(w, analysis.Tree.ZipPathSize, analysis.Tree.Path)
+			c.renderTree(w, analysis.Tree, "", 1)
+		})
+	}
+
+	packages := analysis.Packages
+	if len(packages) > c.limit {
+		packages = packages[:c.limit]
+	}
+
+	logger.LogHeader2("Largest packages")
+	c.renderTable(logger, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, sizeHeader)
+		for _, p := range packages {
+			writeSizeRow(w, p, p.Path)
+		}
+	})
+
+	if len(analysis.Duplicates) == 0 {
+		logger.LogHeader2("No duplicated files")
+		return
+	}
+
+	var wastedSize int64
+	for _, duplicate := range analysis.Duplicates {
+		wastedSize += duplicate.WastedSize
+	}
+
+	duplicates := analysis.Duplicates
+	if len(duplicates) > c.limit {
+		duplicates = duplicates[:c.limit]
+	}
+
+	logger.LogHeader2(fmt.Sprintf("Duplicated files: %s wasted", formatBytes(wastedSize)))
+	c.renderTable(logger, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "WASTED\tCOPIES\tPATHS")
+		for _, duplicate := range duplicates {
+			for i, path := range duplicate.Paths {
+				if i == 0 {
+					fmt.Fprintf(w, "%s\t%d\t%s\n", formatBytes(duplicate.WastedSize), len(duplicate.Paths), path)
+					continue
+				}
+
+				fmt.Fprintf(w, "\t\t%s\n", path)
+			}
+		}
+	})
+}
+
+// renderTree writes the children of a node to the table, down to the configured depth
+func (c *AnalyzeCommand) renderTree(w *tabwriter.Writer, node *io.ZipTreeNode, indent string, depth int) {
+	children := node.Children
+	if len(children) > c.limit {
+		children = children[:c.limit]
+	}
+
+	for i, child := range children {
+		branch, childIndent := "├── ", "│   "
+		if i == len(children)-1 && len(children) == len(node.Children) {
+			branch, childIndent = "└── ", "    "
+		}
+
+		name := child.Path[strings.LastIndex(child.Path, "/")+1:]
+		if len(child.Children) > 0 {
+			name += "/"
+		}
+
+		writeSizeRow(w, child.ZipPathSize, indent+branch+name)
+		if depth < c.depth {
+			c.renderTree(w, child, indent+childIndent, depth+1)
+		}
+	}
+
+	if remaining := node.Children[len(children):]; len(remaining) > 0 {
+		var other io.ZipPathSize
+		for _, child := range remaining {
+			other.CompressedSize += child.CompressedSize
+			other.UncompressedSize += child.UncompressedSize
+		}
+		if other.CompressedSize > 0 {
+			other.CompressionRatio = float64(other.UncompressedSize) / float64(other.CompressedSize)
+		}
+
+		writeSizeRow(w, other, fmt.Sprintf("%s└── (%d more)", indent, len(remaining)))
+	}
+}
+
+// renderTable outputs the rows written by render as an aligned table
+func (c *AnalyzeCommand) renderTable(logger *ui.ZerologUi, render func(w *tabwriter.Writer)) {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	render(w)
+	w.Flush()
+
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		logger.Output(line)
+	}
+}
+
+// sizeHeader is the header row of tables written with writeSizeRow
+const sizeHeader = "SIZE\tCOMPRESSED\tRATIO\tPATH"
+
+// writeSizeRow writes the sizes of a path as a table row
+func writeSizeRow(w *tabwriter.Writer, size io.ZipPathSize, label string) {
+	fmt.Fprintf(w, "%s\t%s\t%.1fx\t%s\n", formatBytes(size.UncompressedSize), formatBytes(size.CompressedSize), size.CompressionRatio, label)
+}
+
+// formatBytes formats a size in bytes using the largest fitting unit
+func formatBytes(size int64) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1fMB", float64(size)/1024/1024)
+	case size >= 1024:
+		return fmt.Sprintf("%.1fKB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%dB", size)
+	}
+}
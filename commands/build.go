@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"lambda-builder/builders"
 	"lambda-builder/io"
@@ -16,20 +17,54 @@ import (
 	flag "github.com/spf13/pflag"
 )
 
+const (
+	// ExitCodeBuildFailed is returned when the build image fails to build
+	ExitCodeBuildFailed = 2
+
+	// ExitCodeExtractFailed is returned when the artifact cannot be extracted from the build image
+	ExitCodeExtractFailed = 3
+
+	// ExitCodePackageFailed is returned when the extracted artifact cannot be processed
+	ExitCodePackageFailed = 4
+
+	// ExitCodeImageFailed is returned when the run image fails to build
+	ExitCodeImageFailed = 5
+
+	// ExitCodeSizeLimitSurpassed is returned when an artifact surpasses a
+	// size limit and --fail-on-size-limit is specified
+	ExitCodeSizeLimitSurpassed = 6
+)
+
 type BuildCommand struct {
 	command.Meta
 
+	all              bool
+	architecture     string
 	buildEnv         []string
 	builder          string
 	buildImage       string
+	cache            bool
+	engine           string
+	failOnSizeLimit  bool
 	generateRunImage bool
 	handler          string
 	imageEnv         []string
 	imageTag         string
+	keepBuildImage   bool
 	labels           []string
+	layer            bool
+	layerFunction    bool
+	layerOutput      string
+	maxUnzippedSize  int64
+	maxZipSize       int64
+	output           string
+	parallelism      int
 	port             int
 	quiet            bool
+	run              bool
 	runImage         string
+	slim             bool
+	watch            bool
 	workingDirectory string
 	writeProcfile    bool
 }
@@ -49,7 +84,10 @@ func (c *BuildCommand) Help() string {
 func (c *BuildCommand) Examples() map[string]string {
 	appName := os.Getenv("CLI_APP_NAME")
 	return map[string]string{
-		"Builds a lambda.zip for the current directory": fmt.Sprintf("%s %s", appName, c.Name()),
+		"Builds a lambda.zip for the current directory":        fmt.Sprintf("%s %s", appName, c.Name()),
+		"Builds every function found in the current directory": fmt.Sprintf("%s %s --all", appName, c.Name()),
+		"Builds a versioned zip into the dist directory":       fmt.Sprintf("%s %s --output 'dist/{{name}}-{{version}}-{{arch}}.zip'", appName, c.Name()),
+		"Rebuilds and restarts the function on changes":        fmt.Sprintf("%s %s --watch --generate-image --run", appName, c.Name()),
 	}
 }
 
@@ -73,13 +111,29 @@ func (c *BuildCommand) FlagSet() *flag.FlagSet {
 	}
 
 	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
+	f.BoolVar(&c.all, "all", false, "build every function found within the working directory")
+	f.BoolVar(&c.cache, "cache", false, "install dependencies in a cached layer and keep the build image between builds")
+	f.BoolVar(&c.failOnSizeLimit, "fail-on-size-limit", false, "exit non-zero when an artifact surpasses a size limit")
 	f.BoolVar(&c.generateRunImage, "generate-image", false, "build a docker image")
+	f.BoolVar(&c.keepBuildImage, "keep-build-image", false, "keep the intermediate build image after the build completes")
+	f.BoolVar(&c.layer, "layer", false, "build a lambda layer containing only dependencies")
+	f.BoolVar(&c.layerFunction, "layer-with-function", false, "also build a function zip containing only app code when building a layer")
 	f.BoolVar(&c.quiet, "quiet", false, "run builder in quiet mode")
+	f.BoolVar(&c.run, "run", false, "start the run image after each build in watch mode, publishing the invoke api on the --port or 9001")
+	f.BoolVar(&c.slim, "slim", false, "remove caches, tests, docs and runtime-provided sdks from the artifacts")
+	f.BoolVar(&c.watch, "watch", false, "rebuild whenever files in the working directory change")
 	f.BoolVar(&c.writeProcfile, "write-procfile", false, "writes a Procfile if a handler is specified or detected")
+	f.Int64Var(&c.maxUnzippedSize, "max-unzipped-size", 0, fmt.Sprintf("maximum unzipped size of an artifact in MB (default %d)", builders.DefaultMaxUnzippedSize))
+	f.Int64Var(&c.maxZipSize, "max-zip-size", 0, fmt.Sprintf("maximum size of an artifact in MB (default %d)", builders.DefaultMaxZipSize))
+	f.IntVar(&c.parallelism, "parallelism", 4, "maximum number of functions to build concurrently when building with --all")
 	f.IntVar(&c.port, "port", -1, "set the default port for the lambda to listen on")
+	f.StringVar(&c.architecture, "arch", "", "set the lambda architecture to build for (x86_64 or arm64)")
 	f.StringVar(&c.builder, "builder", "", "set the builder to use")
 	f.StringVar(&c.buildImage, "build-image", "", "set the build-image to use")
+	f.StringVar(&c.engine, "engine", "", "set the container engine to use")
 	f.StringVar(&c.handler, "handler", "", "handler override to specify as the default command to run in a built image")
+	f.StringVar(&c.layerOutput, "layer-output", "", "path to write the layer.zip to, supporting {{name}}, {{version}}, {{arch}}, {{builder}} and {{id}} placeholders")
+	f.StringVar(&c.output, "output", "", "path to write the lambda.zip to, supporting {{name}}, {{version}}, {{arch}}, {{builder}} and {{id}} placeholders")
 	f.StringVar(&c.runImage, "run-image", "", "set the run-image to use")
 	f.StringVar(&c.workingDirectory, "working-directory", workingDirectory, "working directory")
 	f.StringVarP(&c.imageTag, "tag", "t", "", "name and optionally a tag in the 'name:tag' format")
@@ -93,20 +147,36 @@ func (c *BuildCommand) AutocompleteFlags() complete.Flags {
 	return command.MergeAutocompleteFlags(
 		c.Meta.AutocompleteFlags(command.FlagSetClient),
 		complete.Flags{
-			"--build-env":         complete.PredictAnything,
-			"--build-image":       complete.PredictAnything,
-			"--builder":           complete.PredictSet("dotnet", "go", "nodejs", "python", "ruby"),
-			"--generate-image":    complete.PredictNothing,
-			"--handler":           complete.PredictAnything,
-			"--image-env":         complete.PredictAnything,
-			"--label":             complete.PredictAnything,
-			"--port":              complete.PredictAnything,
-			"--quiet":             complete.PredictNothing,
-			"--run-image":         complete.PredictAnything,
-			"-t":                  complete.PredictAnything,
-			"--tag":               complete.PredictAnything,
-			"--working-directory": complete.PredictAnything,
-			"--write-procfile":    complete.PredictNothing,
+			"--all":                 complete.PredictNothing,
+			"--arch":                complete.PredictSet(builders.Architectures...),
+			"--build-env":           complete.PredictAnything,
+			"--build-image":         complete.PredictAnything,
+			"--builder":             complete.PredictSet(builders.BuilderNames()...),
+			"--cache":               complete.PredictNothing,
+			"--engine":              complete.PredictSet(builders.ContainerEngines...),
+			"--fail-on-size-limit":  complete.PredictNothing,
+			"--generate-image":      complete.PredictNothing,
+			"--handler":             complete.PredictAnything,
+			"--image-env":           complete.PredictAnything,
+			"--keep-build-image":    complete.PredictNothing,
+			"--label":               complete.PredictAnything,
+			"--layer":               complete.PredictNothing,
+			"--layer-output":        complete.PredictFiles("*.zip"),
+			"--layer-with-function": complete.PredictNothing,
+			"--max-unzipped-size":   complete.PredictAnything,
+			"--max-zip-size":        complete.PredictAnything,
+			"--output":              complete.PredictFiles("*.zip"),
+			"--parallelism":         complete.PredictAnything,
+			"--port":                complete.PredictAnything,
+			"--quiet":               complete.PredictNothing,
+			"--run":                 complete.PredictNothing,
+			"--run-image":           complete.PredictAnything,
+			"--slim":                complete.PredictNothing,
+			"-t":                    complete.PredictAnything,
+			"--tag":                 complete.PredictAnything,
+			"--watch":               complete.PredictNothing,
+			"--working-directory":   complete.PredictAnything,
+			"--write-procfile":      complete.PredictNothing,
 		},
 	)
 }
@@ -138,116 +208,262 @@ func (c *BuildCommand) Run(args []string) int {
 		return 1
 	}
 
-	if io.FileExistsInDirectory(c.workingDirectory, "lambda.zip") {
-		c.Ui.Warn("Removing existing lambda.zip from working directory")
-		os.Remove(filepath.Join(c.workingDirectory, "lambda.zip"))
+	if c.layerFunction && !c.layer {
+		c.Ui.Error("The --layer-with-function flag requires the --layer flag")
+		return 1
+	}
+
+	if c.layer && c.generateRunImage {
+		c.Ui.Error("The --generate-image flag cannot be combined with the --layer flag")
+		return 1
+	}
+
+	if c.run && !(c.watch && c.generateRunImage) {
+		c.Ui.Error("The --run flag requires the --watch and --generate-image flags")
+		return 1
+	}
+
+	if c.watch && c.all {
+		c.Ui.Error("The --watch flag cannot be combined with the --all flag")
+		return 1
+	}
+
+	if c.all {
+		return c.buildAll(logger)
+	}
+
+	if c.watch {
+		return c.buildWatch(logger)
 	}
 
-	identifier := uuid.New().String()
-	config := builders.Config{
+	result := c.buildFunction(logger, c.newConfig(c.workingDirectory, c.imageTag))
+	return result.ExitCode
+}
+
+// buildResult holds the outcome of building a single function
+type buildResult struct {
+	// Builder is the name of the detected builder
+	Builder string
+
+	// Duration is the time taken to build the function
+	Duration time.Duration
+
+	// ExitCode is the exit code the build would return
+	ExitCode int
+
+	// Name is the name of the function
+	Name string
+
+	// Size is the combined size of the written artifacts in bytes
+	Size int64
+}
+
+// newConfig returns the build config for a function in the given directory
+func (c *BuildCommand) newConfig(workingDirectory string, imageTag string) builders.Config {
+	return builders.Config{
+		Architecture:      c.architecture,
 		BuildEnv:          c.buildEnv,
 		Builder:           c.builder,
 		BuilderBuildImage: c.buildImage,
 		BuilderRunImage:   c.runImage,
+		Cache:             c.cache,
+		Engine:            c.engine,
+		FailOnSizeLimit:   c.failOnSizeLimit,
 		GenerateRunImage:  c.generateRunImage,
-		Identifier:        identifier,
+		Handler:           c.handler,
+		Identifier:        uuid.New().String(),
 		ImageEnv:          c.imageEnv,
 		ImageLabels:       c.labels,
-		ImageTag:          c.imageTag,
+		ImageTag:          imageTag,
+		KeepBuildImage:    c.keepBuildImage,
+		Layer:             c.layer,
+		LayerFunction:     c.layerFunction,
+		LayerOutput:       c.layerOutput,
+		MaxUnzippedSize:   c.maxUnzippedSize,
+		MaxZipSize:        c.maxZipSize,
+		Output:            c.output,
 		Port:              c.port,
 		RunQuiet:          c.quiet,
-		WorkingDirectory:  c.workingDirectory,
+		Slim:              c.slim,
+		WorkingDirectory:  workingDirectory,
 		WriteProcfile:     c.writeProcfile,
 	}
+}
+
+// buildFunction builds the function described by config, logging to logger
+func (c *BuildCommand) buildFunction(logger *ui.ZerologUi, config builders.Config) (result buildResult) {
+	start := time.Now()
+	config.Logger = logger
+	result = buildResult{
+		ExitCode: 1,
+		Name:     filepath.Base(config.WorkingDirectory),
+	}
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
 
 	logger.LogHeader1("Detecting builder")
-	builder, err := detectBuilder(config)
+	builder, err := builders.DetectBuilder(config)
 	if err != nil {
-		c.Ui.Error(err.Error())
-		return 1
+		logger.Error(err.Error())
+		return result
 	}
 
-	c.Ui.Info(fmt.Sprintf("Detected %s builder", builder.Name()))
+	result.Builder = builder.Name()
+	logger.Info(fmt.Sprintf("Detected %s builder", builder.Name()))
 
 	logger.LogHeader1(fmt.Sprintf("Building app with image %s", builder.GetBuildImage()))
 	if err := builder.Execute(); err != nil {
-		c.Ui.Error(err.Error())
-		return 1
+		result.ExitCode = c.renderBuildError(logger, err)
+		return result
 	}
 
-	zipPath := filepath.Join(c.workingDirectory, "lambda.zip")
-	logger.LogHeader1(fmt.Sprintf("Wrote %s", zipPath))
-	sizeInBytes, err := io.FileSize(zipPath)
+	artifactPaths, err := builders.GetArtifactPaths(builder)
 	if err != nil {
-		c.Ui.Error(fmt.Sprintf("Error getting filesize for %s: %s", zipPath, err.Error()))
-		return 1
+		logger.Error(err.Error())
+		return result
 	}
 
-	sizeInKB := io.BytesToKilobytes(sizeInBytes)
-	sizeInMB := io.BytesToMegabytes(sizeInBytes)
-	if sizeInMB >= 50 {
-		c.Ui.Warn(fmt.Sprintf("Surpassed AWS Lambda 50MB zip file limit: %dMB (%dKB)", sizeInMB, sizeInKB))
-		c.Ui.Warn("Consider using Docker Images for lambda function distribution")
-	} else {
-		c.Ui.Info(fmt.Sprintf("Current zip file size: %dMB (%dKB)", sizeInMB, sizeInKB))
+	sizePolicy, err := builders.GetSizePolicy(builder.GetConfig())
+	if err != nil {
+		logger.Error(err.Error())
+		return result
 	}
 
-	return 0
-}
+	sizeLimitSurpassed := false
+	combinedUnzippedSizeInBytes := int64(0)
+	for _, artifact := range config.GetArtifacts() {
+		zipPath := artifactPaths[artifact]
+		logger.LogHeader1(fmt.Sprintf("Wrote %s", zipPath))
+		sizeInBytes, unzippedSizeInBytes, surpassed, err := c.checkArtifactSize(logger, sizePolicy, zipPath)
+		if err != nil {
+			logger.Error(err.Error())
+			return result
+		}
 
-func detectBuilder(config builders.Config) (builders.Builder, error) {
-	var builder builders.Builder
-	var err error
-	bs := []builders.Builder{}
+		result.Size += sizeInBytes
+		combinedUnzippedSizeInBytes += unzippedSizeInBytes
+		sizeLimitSurpassed = sizeLimitSurpassed || surpassed
 
-	lambdaYML, err := builders.ParseLambdaYML(config)
-	if err != nil {
-		return nil, err
+		sha256, err := io.FileSHA256(zipPath)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Error computing checksum for %s: %s", zipPath, err.Error()))
+			return result
+		}
+
+		logger.Info(fmt.Sprintf("SHA-256: %s", sha256))
 	}
 
-	builder, err = builders.NewDotnetBuilder(config)
-	if err != nil {
-		return nil, err
+	// the unzipped size limit applies to the function and its layers combined
+	if len(config.GetArtifacts()) > 1 {
+		surpassed := c.checkCombinedUnzippedSize(logger, sizePolicy, combinedUnzippedSizeInBytes)
+		sizeLimitSurpassed = sizeLimitSurpassed || surpassed
 	}
-	bs = append(bs, builder)
 
-	builder, err = builders.NewGoBuilder(config)
-	if err != nil {
-		return nil, err
+	if sizeLimitSurpassed && sizePolicy.FailOnSizeLimit {
+		logger.Error("Failing build as a size limit was surpassed")
+		result.ExitCode = ExitCodeSizeLimitSurpassed
+		return result
 	}
-	bs = append(bs, builder)
 
-	builder, err = builders.NewNodejsBuilder(config)
+	result.ExitCode = 0
+	return result
+}
+
+// checkArtifactSize reports the size of an artifact against the size policy,
+// returning the size and unzipped size of the artifact and whether a limit was surpassed
+func (c *BuildCommand) checkArtifactSize(logger *ui.ZerologUi, policy builders.SizePolicy, zipPath string) (int64, int64, bool, error) {
+	sizeInBytes, err := io.FileSize(zipPath)
 	if err != nil {
-		return nil, err
+		return 0, 0, false, fmt.Errorf("error getting filesize for %s: %w", zipPath, err)
 	}
-	bs = append(bs, builder)
 
-	builder, err = builders.NewPythonBuilder(config)
+	unzippedSizeInBytes, err := io.ZipUncompressedSize(zipPath)
 	if err != nil {
-		return nil, err
+		return 0, 0, false, fmt.Errorf("error getting unzipped size for %s: %w", zipPath, err)
+	}
+
+	surpassed := false
+	sizeInKB := io.BytesToKilobytes(sizeInBytes)
+	sizeInMB := io.BytesToMegabytes(sizeInBytes)
+	if sizeInBytes > policy.MaxZipSize*1024*1024 {
+		surpassed = true
+		logger.Warn(fmt.Sprintf("Surpassed %dMB zip file limit: %dMB (%dKB)", policy.MaxZipSize, sizeInMB, sizeInKB))
+		logger.Warn("Consider using Docker Images for lambda function distribution")
+	} else {
+		logger.Info(fmt.Sprintf("Current zip file size: %dMB (%dKB)", sizeInMB, sizeInKB))
+	}
+
+	unzippedSizeInKB := io.BytesToKilobytes(unzippedSizeInBytes)
+	unzippedSizeInMB := io.BytesToMegabytes(unzippedSizeInBytes)
+	if unzippedSizeInBytes > policy.MaxUnzippedSize*1024*1024 {
+		surpassed = true
+		logger.Warn(fmt.Sprintf("Surpassed %dMB unzipped size limit: %dMB (%dKB)", policy.MaxUnzippedSize, unzippedSizeInMB, unzippedSizeInKB))
+	} else {
+		logger.Info(fmt.Sprintf("Current unzipped size: %dMB (%dKB)", unzippedSizeInMB, unzippedSizeInKB))
 	}
-	bs = append(bs, builder)
 
-	builder, err = builders.NewRubyBuilder(config)
+	if !surpassed {
+		return sizeInBytes, unzippedSizeInBytes, false, nil
+	}
+
+	packages, err := io.ZipLargestPackages(zipPath, 10)
 	if err != nil {
-		return nil, err
+		return 0, 0, true, fmt.Errorf("error reading %s: %w", zipPath, err)
 	}
-	bs = append(bs, builder)
 
-	selectedImage := lambdaYML.Builder
-	if config.Builder != "" {
-		selectedImage = config.Builder
+	logger.Warn("Largest contributors to the unzipped size:")
+	for _, p := range packages {
+		logger.Warn(fmt.Sprintf("  %6dKB  %s", io.BytesToKilobytes(p.UncompressedSize), p.Path))
 	}
-	for _, builder := range bs {
-		if selectedImage != "" && selectedImage != builder.Name() {
-			continue
-		}
 
-		if builder.Detect() {
-			return builder, nil
+	return sizeInBytes, unzippedSizeInBytes, true, nil
+}
+
+// checkCombinedUnzippedSize reports the unzipped size of all artifacts against
+// the size policy, returning whether the limit was surpassed
+func (c *BuildCommand) checkCombinedUnzippedSize(logger *ui.ZerologUi, policy builders.SizePolicy, unzippedSizeInBytes int64) bool {
+	unzippedSizeInKB := io.BytesToKilobytes(unzippedSizeInBytes)
+	unzippedSizeInMB := io.BytesToMegabytes(unzippedSizeInBytes)
+	if unzippedSizeInBytes > policy.MaxUnzippedSize*1024*1024 {
+		logger.Warn(fmt.Sprintf("Surpassed %dMB combined unzipped size limit: %dMB (%dKB)", policy.MaxUnzippedSize, unzippedSizeInMB, unzippedSizeInKB))
+		return true
+	}
+
+	logger.Info(fmt.Sprintf("Combined unzipped size: %dMB (%dKB)", unzippedSizeInMB, unzippedSizeInKB))
+	return false
+}
+
+// renderBuildError outputs the error and returns the exit code for the failed phase
+func (c *BuildCommand) renderBuildError(logger *ui.ZerologUi, err error) int {
+	logger.Error(err.Error())
+
+	var buildErr *builders.BuildError
+	if !errors.As(err, &buildErr) {
+		return 1
+	}
+
+	if buildErr.ExitCode != 0 {
+		logger.Error(fmt.Sprintf("Exit code: %d", buildErr.ExitCode))
+	}
+
+	if len(buildErr.Output) > 0 {
+		logger.Error(fmt.Sprintf("Last %d lines of output:", len(buildErr.Output)))
+		for _, line := range buildErr.Output {
+			logger.Error(line)
 		}
 	}
 
-	return nil, errors.New("no builder detected")
+	switch {
+	case errors.Is(err, builders.ErrBuildFailed):
+		return ExitCodeBuildFailed
+	case errors.Is(err, builders.ErrExtractFailed):
+		return ExitCodeExtractFailed
+	case errors.Is(err, builders.ErrPackageFailed):
+		return ExitCodePackageFailed
+	case errors.Is(err, builders.ErrImageFailed):
+		return ExitCodeImageFailed
+	}
+
+	return 1
 }
@@ -0,0 +1,217 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"lambda-builder/builders"
+	"lambda-builder/io"
+	"lambda-builder/ui"
+)
+
+// skippedFunctionDirectories are never searched for functions
+var skippedFunctionDirectories = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+}
+
+var invalidImageNameCharacters = regexp.MustCompile(`[^a-z0-9._/-]+`)
+
+// buildAll builds every function found within the working directory
+func (c *BuildCommand) buildAll(logger *ui.ZerologUi) int {
+	if c.imageTag != "" {
+		logger.Error("The --tag flag cannot be combined with the --all flag")
+		return 1
+	}
+
+	if c.parallelism < 1 {
+		logger.Error("The --parallelism flag must be at least 1")
+		return 1
+	}
+
+	logger.LogHeader1("Discovering functions")
+	functions, err := c.discoverFunctions(c.workingDirectory)
+	if err != nil {
+		logger.Error(err.Error())
+		return 1
+	}
+
+	if len(functions) == 0 {
+		logger.Error("No functions found in working directory")
+		return 1
+	}
+
+	for _, function := range functions {
+		logger.Info(fmt.Sprintf("Found %s", c.functionName(function)))
+	}
+
+	if err := c.checkArtifactCollisions(functions); err != nil {
+		logger.Error(err.Error())
+		return 1
+	}
+
+	results := make([]buildResult, len(functions))
+	semaphore := make(chan struct{}, c.parallelism)
+	var wg sync.WaitGroup
+	for i, function := range functions {
+		wg.Add(1)
+		go func(i int, function string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			name := c.functionName(function)
+			config := c.newConfig(function, c.functionImageTag(name))
+
+			results[i] = c.buildFunction(logger.Field("function", name), config)
+			results[i].Name = name
+		}(i, function)
+	}
+	wg.Wait()
+
+	return c.renderSummary(logger, results)
+}
+
+// discoverFunctions returns the directories below root that contain
+// a lambda.yml or a project a builder can detect
+func (c *BuildCommand) discoverFunctions(root string) ([]string, error) {
+	functions := []string{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		if path != root && (strings.HasPrefix(d.Name(), ".") || skippedFunctionDirectories[d.Name()]) {
+			return filepath.SkipDir
+		}
+
+		if !c.isFunction(path) {
+			return nil
+		}
+
+		functions = append(functions, path)
+		return filepath.SkipDir
+	})
+
+	return functions, err
+}
+
+// checkArtifactCollisions returns an error if artifacts of two functions would
+// be written to the same path, such as when an output path without a unique
+// placeholder is specified, or {{name}} is used by functions sharing a directory name
+func (c *BuildCommand) checkArtifactCollisions(functions []string) error {
+	written := map[string]string{}
+	for _, function := range functions {
+		builder, err := builders.DetectBuilder(c.newConfig(function, ""))
+		if err != nil {
+			// the error is reported when the function is built
+			continue
+		}
+
+		artifactPaths, err := builders.GetArtifactPaths(builder)
+		if err != nil {
+			continue
+		}
+
+		artifacts := make([]string, 0, len(artifactPaths))
+		for artifact := range artifactPaths {
+			artifacts = append(artifacts, artifact)
+		}
+		sort.Strings(artifacts)
+
+		name := c.functionName(function)
+		for _, artifact := range artifacts {
+			path := artifactPaths[artifact]
+			description := fmt.Sprintf("the %s of %s", artifact, name)
+			if existing, ok := written[path]; ok {
+				return fmt.Errorf("%s and %s cannot both be written to %s", existing, description, path)
+			}
+
+			written[path] = description
+		}
+	}
+
+	return nil
+}
+
+// isFunction returns whether the directory contains a buildable function
+func (c *BuildCommand) isFunction(directory string) bool {
+	if info, err := os.Stat(filepath.Join(directory, "lambda.yml")); err == nil && info.Mode().IsRegular() {
+		return true
+	}
+
+	_, err := builders.DetectBuilder(c.newConfig(directory, ""))
+	return err == nil
+}
+
+// functionName returns the name of a function relative to the working directory
+func (c *BuildCommand) functionName(directory string) string {
+	name, err := filepath.Rel(c.workingDirectory, directory)
+	if err != nil || name == "." {
+		return filepath.Base(directory)
+	}
+
+	return filepath.ToSlash(name)
+}
+
+// functionImageTag returns a unique image tag for a function so that
+// functions sharing a directory name do not overwrite each others images
+func (c *BuildCommand) functionImageTag(name string) string {
+	repository := invalidImageNameCharacters.ReplaceAllString(strings.ToLower(name), "-")
+	return fmt.Sprintf("lambda-builder/%s:latest", strings.Trim(repository, "-./"))
+}
+
+// renderSummary outputs a table of build results and returns the exit code
+func (c *BuildCommand) renderSummary(logger *ui.ZerologUi, results []buildResult) int {
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tBUILDER\tRESULT\tSIZE\tDURATION")
+
+	exitCode := 0
+	failed := 0
+	for _, result := range results {
+		status := "ok"
+		if result.ExitCode != 0 {
+			status = fmt.Sprintf("failed (exit code %d)", result.ExitCode)
+			exitCode = 1
+			failed++
+		}
+
+		size := "-"
+		if result.Size > 0 {
+			size = fmt.Sprintf("%dMB (%dKB)", io.BytesToMegabytes(result.Size), io.BytesToKilobytes(result.Size))
+		}
+
+		builder := result.Builder
+		if builder == "" {
+			builder = "-"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", result.Name, builder, status, size, result.Duration.Round(100*time.Millisecond))
+	}
+	w.Flush()
+
+	logger.LogHeader1(fmt.Sprintf("Built %d of %d functions", len(results)-failed, len(results)))
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		logger.Output(line)
+	}
+
+	return exitCode
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"lambda-builder/builders"
+	"lambda-builder/ui"
+)
+
+// defaultRunPort is the port the invoke api is published on when --run is
+// specified without --port, matching the port used by the run images
+const defaultRunPort = 9001
+
+// buildWatch builds the function and rebuilds it whenever files within the
+// working directory change, until interrupted. Dependencies are installed in
+// a cached layer so that they are only reinstalled when dependency files change
+func (c *BuildCommand) buildWatch(logger *ui.ZerologUi) int {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	port := defaultRunPort
+	if c.port > 0 {
+		port = c.port
+	}
+
+	var container *builders.FunctionContainer
+	defer func() {
+		if container != nil {
+			removeFunctionContainer(logger, container)
+		}
+	}()
+
+	build := func() {
+		config := c.newConfig(c.workingDirectory, c.imageTag)
+		config.Cache = true
+		if result := c.buildFunction(logger, config); result.ExitCode != 0 {
+			logger.Warn("Build failed, waiting for changes")
+			return
+		}
+
+		if !c.run {
+			return
+		}
+
+		// the previous container is removed first as it holds the published port
+		if container != nil {
+			removeFunctionContainer(logger, container)
+			container = nil
+		}
+
+		logger.LogHeader1(fmt.Sprintf("Starting %s", config.GetImageTag()))
+		started, err := builders.StartFunctionContainerOnPort(ctx, config, []string{}, port)
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+
+		container = started
+		logger.Info(fmt.Sprintf("Invoke api listening on %s", container.Endpoint))
+	}
+
+	build()
+
+	logger.LogHeader1(fmt.Sprintf("Watching %s for changes", c.workingDirectory))
+	err := builders.WatchWorkingDirectory(ctx, c.newConfig(c.workingDirectory, c.imageTag), func() {
+		logger.LogHeader1("Detected changes, rebuilding")
+		build()
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error watching working directory: %s", err.Error()))
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,265 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"lambda-builder/builders"
+	"lambda-builder/ui"
+
+	"github.com/josegonzalez/cli-skeleton/command"
+	"github.com/posener/complete"
+	flag "github.com/spf13/pflag"
+)
+
+type InvokeCommand struct {
+	command.Meta
+
+	architecture     string
+	build            bool
+	buildEnv         []string
+	builder          string
+	buildImage       string
+	engine           string
+	env              []string
+	event            string
+	handler          string
+	imageTag         string
+	quiet            bool
+	runImage         string
+	workingDirectory string
+}
+
+func (c *InvokeCommand) Name() string {
+	return "invoke"
+}
+
+func (c *InvokeCommand) Synopsis() string {
+	return "Invokes a built function locally"
+}
+
+func (c *InvokeCommand) Help() string {
+	return command.CommandHelp(c)
+}
+
+func (c *InvokeCommand) Examples() map[string]string {
+	appName := os.Getenv("CLI_APP_NAME")
+	return map[string]string{
+		"Invokes the function with an empty event":            fmt.Sprintf("%s %s", appName, c.Name()),
+		"Invokes the function with an event from a file":      fmt.Sprintf("%s %s --event event.json", appName, c.Name()),
+		"Invokes the function with an event from stdin":       fmt.Sprintf("echo '{\"name\": \"World\"}' | %s %s --event -", appName, c.Name()),
+		"Rebuilds the run image before invoking the function": fmt.Sprintf("%s %s --build", appName, c.Name()),
+	}
+}
+
+func (c *InvokeCommand) Arguments() []command.Argument {
+	args := []command.Argument{}
+	return args
+}
+
+func (c *InvokeCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *InvokeCommand) ParsedArguments(args []string) (map[string]command.Argument, error) {
+	return command.ParseArguments(args, c.Arguments())
+}
+
+func (c *InvokeCommand) FlagSet() *flag.FlagSet {
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+
+	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
+	f.BoolVar(&c.build, "build", false, "build the run image even if it already exists")
+	f.BoolVar(&c.quiet, "quiet", false, "run builder in quiet mode")
+	f.StringVar(&c.architecture, "arch", "", "set the lambda architecture to build for (x86_64 or arm64)")
+	f.StringVar(&c.builder, "builder", "", "set the builder to use")
+	f.StringVar(&c.buildImage, "build-image", "", "set the build-image to use")
+	f.StringVar(&c.engine, "engine", "", "set the container engine to use")
+	f.StringVar(&c.event, "event", "", "path to a json file containing the event, or - to read from stdin (default {})")
+	f.StringVar(&c.handler, "handler", "", "handler to invoke, defaulting to the command of the run image")
+	f.StringVar(&c.runImage, "run-image", "", "set the run-image to use")
+	f.StringVar(&c.workingDirectory, "working-directory", workingDirectory, "working directory")
+	f.StringVarP(&c.imageTag, "tag", "t", "", "name and optionally a tag in the 'name:tag' format of the run image")
+	f.StringArrayVar(&c.buildEnv, "build-env", []string{}, "environment variables to be set for the build context")
+	f.StringArrayVar(&c.env, "env", []string{}, "environment variables to be set for the function")
+	return f
+}
+
+func (c *InvokeCommand) AutocompleteFlags() complete.Flags {
+	return command.MergeAutocompleteFlags(
+		c.Meta.AutocompleteFlags(command.FlagSetClient),
+		complete.Flags{
+			"--arch":              complete.PredictSet(builders.Architectures...),
+			"--build":             complete.PredictNothing,
+			"--build-env":         complete.PredictAnything,
+			"--build-image":       complete.PredictAnything,
+			"--builder":           complete.PredictSet(builders.BuilderNames()...),
+			"--engine":            complete.PredictSet(builders.ContainerEngines...),
+			"--env":               complete.PredictAnything,
+			"--event":             complete.PredictFiles("*.json"),
+			"--handler":           complete.PredictAnything,
+			"--quiet":             complete.PredictNothing,
+			"--run-image":         complete.PredictAnything,
+			"-t":                  complete.PredictAnything,
+			"--tag":               complete.PredictAnything,
+			"--working-directory": complete.PredictAnything,
+		},
+	)
+}
+
+func (c *InvokeCommand) Run(args []string) int {
+	flags := c.FlagSet()
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		c.Ui.Error(err.Error())
+		c.Ui.Error(command.CommandErrorText(c))
+		return 1
+	}
+
+	var err error
+	c.workingDirectory, err = filepath.Abs(c.workingDirectory)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	logger, ok := c.Ui.(*ui.ZerologUi)
+	if !ok {
+		c.Ui.Error("Unable to fetch logger from cli")
+		return 1
+	}
+
+	event, err := c.readEvent()
+	if err != nil {
+		logger.Error(err.Error())
+		return 1
+	}
+
+	config, exitCode := c.prepareRunImage(logger)
+	if exitCode != 0 {
+		return exitCode
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	logger.LogHeader1(fmt.Sprintf("Starting %s", config.GetImageTag()))
+	container, err := builders.StartFunctionContainer(ctx, config, c.env)
+	if err != nil {
+		logger.Error(err.Error())
+		return 1
+	}
+
+	defer removeFunctionContainer(logger, container)
+
+	logger.LogHeader1(fmt.Sprintf("Invoking %s", container.Handler))
+	result, err := container.Invoke(ctx, event)
+	if err != nil {
+		logger.Error(err.Error())
+		return 1
+	}
+
+	return c.renderResult(logger, result)
+}
+
+// readEvent returns the event to invoke the function with
+func (c *InvokeCommand) readEvent() ([]byte, error) {
+	if c.event == "" {
+		return []byte("{}"), nil
+	}
+
+	var event []byte
+	var err error
+	if c.event == "-" {
+		event, err = io.ReadAll(os.Stdin)
+	} else {
+		event, err = os.ReadFile(c.event)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading event: %w", err)
+	}
+
+	if !json.Valid(event) {
+		return nil, fmt.Errorf("event is not valid json")
+	}
+
+	return event, nil
+}
+
+// prepareRunImage builds the run image unless it already exists,
+// returning the config describing it and a non-zero exit code on failure
+func (c *InvokeCommand) prepareRunImage(logger *ui.ZerologUi) (builders.Config, int) {
+	build := &BuildCommand{
+		Meta:             c.Meta,
+		architecture:     c.architecture,
+		buildEnv:         c.buildEnv,
+		builder:          c.builder,
+		buildImage:       c.buildImage,
+		engine:           c.engine,
+		generateRunImage: true,
+		handler:          c.handler,
+		port:             -1,
+		quiet:            c.quiet,
+		runImage:         c.runImage,
+	}
+
+	config := build.newConfig(c.workingDirectory, c.imageTag)
+	config.Logger = logger
+
+	exists, err := builders.RunImageExists(config)
+	if err != nil {
+		logger.Error(err.Error())
+		return config, 1
+	}
+
+	if exists && !c.build {
+		logger.Info(fmt.Sprintf("Using existing image %s", config.GetImageTag()))
+	} else if result := build.buildFunction(logger, config); result.ExitCode != 0 {
+		return config, result.ExitCode
+	}
+
+	return config, 0
+}
+
+// renderResult outputs the response and logs of an invocation, returning the exit code
+func (c *InvokeCommand) renderResult(logger *ui.ZerologUi, result builders.InvokeResult) int {
+	logger.LogHeader2(fmt.Sprintf("Response (status %d, %s)", result.StatusCode, result.Duration.Round(time.Millisecond)))
+	logger.Output(string(result.Payload))
+
+	if result.Logs != "" {
+		logger.LogHeader2("Logs")
+		for _, line := range strings.Split(strings.TrimRight(result.Logs, "\n"), "\n") {
+			logger.Output(line)
+		}
+	}
+
+	if result.FunctionError != "" {
+		logger.Error(fmt.Sprintf("Function returned an error: %s", result.FunctionError))
+		return 1
+	}
+
+	if result.StatusCode >= 300 {
+		logger.Error(fmt.Sprintf("Invoke api returned status %d", result.StatusCode))
+		return 1
+	}
+
+	return 0
+}
+
+// removeFunctionContainer removes a started function container, warning on failure
+func removeFunctionContainer(logger *ui.ZerologUi, container *builders.FunctionContainer) {
+	logger.Info(fmt.Sprintf("Removing container %s", container.Name))
+	if err := container.Stop(); err != nil {
+		logger.Warn(fmt.Sprintf("Error removing container: %s", err.Error()))
+	}
+}
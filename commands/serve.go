@@ -0,0 +1,336 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"lambda-builder/builders"
+	"lambda-builder/ui"
+
+	"github.com/josegonzalez/cli-skeleton/command"
+	"github.com/posener/complete"
+	flag "github.com/spf13/pflag"
+)
+
+type ServeCommand struct {
+	command.Meta
+
+	architecture     string
+	buildEnv         []string
+	builder          string
+	buildImage       string
+	engine           string
+	env              []string
+	handler          string
+	imageTag         string
+	listen           string
+	noWatch          bool
+	payloadFormat    string
+	quiet            bool
+	runImage         string
+	workingDirectory string
+}
+
+func (c *ServeCommand) Name() string {
+	return "serve"
+}
+
+func (c *ServeCommand) Synopsis() string {
+	return "Serves a built function over http as an API Gateway proxy"
+}
+
+func (c *ServeCommand) Help() string {
+	return command.CommandHelp(c)
+}
+
+func (c *ServeCommand) Examples() map[string]string {
+	appName := os.Getenv("CLI_APP_NAME")
+	return map[string]string{
+		"Serves the function on http://127.0.0.1:3000":    fmt.Sprintf("%s %s", appName, c.Name()),
+		"Serves the function using REST API proxy events": fmt.Sprintf("%s %s --payload-format-version 1.0", appName, c.Name()),
+		"Serves the function on all interfaces":           fmt.Sprintf("%s %s --listen 0.0.0.0:8080", appName, c.Name()),
+	}
+}
+
+func (c *ServeCommand) Arguments() []command.Argument {
+	args := []command.Argument{}
+	return args
+}
+
+func (c *ServeCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *ServeCommand) ParsedArguments(args []string) (map[string]command.Argument, error) {
+	return command.ParseArguments(args, c.Arguments())
+}
+
+func (c *ServeCommand) FlagSet() *flag.FlagSet {
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+
+	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
+	f.BoolVar(&c.noWatch, "no-watch", false, "do not rebuild the function when files in the working directory change")
+	f.BoolVar(&c.quiet, "quiet", false, "run builder in quiet mode")
+	f.StringVar(&c.architecture, "arch", "", "set the lambda architecture to build for (x86_64 or arm64)")
+	f.StringVar(&c.builder, "builder", "", "set the builder to use")
+	f.StringVar(&c.buildImage, "build-image", "", "set the build-image to use")
+	f.StringVar(&c.engine, "engine", "", "set the container engine to use")
+	f.StringVar(&c.handler, "handler", "", "handler override to specify as the default command to run in a built image")
+	f.StringVar(&c.listen, "listen", "127.0.0.1:3000", "address to serve http requests on")
+	f.StringVar(&c.payloadFormat, "payload-format-version", builders.GatewayPayloadVersion2, "API Gateway payload format version (1.0 or 2.0)")
+	f.StringVar(&c.runImage, "run-image", "", "set the run-image to use")
+	f.StringVar(&c.workingDirectory, "working-directory", workingDirectory, "working directory")
+	f.StringVarP(&c.imageTag, "tag", "t", "", "name and optionally a tag in the 'name:tag' format of the run image")
+	f.StringArrayVar(&c.buildEnv, "build-env", []string{}, "environment variables to be set for the build context")
+	f.StringArrayVar(&c.env, "env", []string{}, "environment variables to be set for the function")
+	return f
+}
+
+func (c *ServeCommand) AutocompleteFlags() complete.Flags {
+	return command.MergeAutocompleteFlags(
+		c.Meta.AutocompleteFlags(command.FlagSetClient),
+		complete.Flags{
+			"--arch":                   complete.PredictSet(builders.Architectures...),
+			"--build-env":              complete.PredictAnything,
+			"--build-image":            complete.PredictAnything,
+			"--builder":                complete.PredictSet(builders.BuilderNames()...),
+			"--engine":                 complete.PredictSet(builders.ContainerEngines...),
+			"--env":                    complete.PredictAnything,
+			"--handler":                complete.PredictAnything,
+			"--listen":                 complete.PredictAnything,
+			"--no-watch":               complete.PredictNothing,
+			"--payload-format-version": complete.PredictSet(builders.GatewayPayloadVersions...),
+			"--quiet":                  complete.PredictNothing,
+			"--run-image":              complete.PredictAnything,
+			"-t":                       complete.PredictAnything,
+			"--tag":                    complete.PredictAnything,
+			"--working-directory":      complete.PredictAnything,
+		},
+	)
+}
+
+func (c *ServeCommand) Run(args []string) int {
+	flags := c.FlagSet()
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		c.Ui.Error(err.Error())
+		c.Ui.Error(command.CommandErrorText(c))
+		return 1
+	}
+
+	var err error
+	c.workingDirectory, err = filepath.Abs(c.workingDirectory)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	if c.payloadFormat != builders.GatewayPayloadVersion1 && c.payloadFormat != builders.GatewayPayloadVersion2 {
+		c.Ui.Error(fmt.Sprintf("Unsupported payload format version: %s", c.payloadFormat))
+		return 1
+	}
+
+	logger, ok := c.Ui.(*ui.ZerologUi)
+	if !ok {
+		c.Ui.Error("Unable to fetch logger from cli")
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	server := &functionServer{
+		logger:        logger,
+		payloadFormat: c.payloadFormat,
+	}
+	defer server.stop()
+
+	config, exitCode := c.startFunction(ctx, logger, server)
+	if exitCode != 0 {
+		return exitCode
+	}
+
+	httpServer := &http.Server{
+		Addr:    c.listen,
+		Handler: server,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- httpServer.ListenAndServe()
+	}()
+
+	logger.LogHeader1(fmt.Sprintf("Serving %s on http://%s", server.container.Handler, c.listen))
+
+	if !c.noWatch {
+		go func() {
+			err := builders.WatchWorkingDirectory(ctx, config, func() {
+				logger.LogHeader1("Detected changes, rebuilding")
+				c.startFunction(ctx, logger, server)
+			})
+			if err != nil {
+				errs <- fmt.Errorf("error watching working directory: %w", err)
+			}
+		}()
+	}
+
+	select {
+	case err := <-errs:
+		logger.Error(err.Error())
+		return 1
+	case <-ctx.Done():
+	}
+
+	logger.LogHeader1("Shutting down")
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Warn(fmt.Sprintf("Error shutting down server: %s", err.Error()))
+	}
+
+	return 0
+}
+
+// startFunction builds the run image and starts it, replacing the container
+// the server forwards requests to. The previous container keeps serving
+// requests should the build fail
+func (c *ServeCommand) startFunction(ctx context.Context, logger *ui.ZerologUi, server *functionServer) (builders.Config, int) {
+	build := &BuildCommand{
+		Meta:             c.Meta,
+		architecture:     c.architecture,
+		buildEnv:         c.buildEnv,
+		builder:          c.builder,
+		buildImage:       c.buildImage,
+		engine:           c.engine,
+		generateRunImage: true,
+		handler:          c.handler,
+		port:             -1,
+		quiet:            c.quiet,
+		runImage:         c.runImage,
+	}
+
+	config := build.newConfig(c.workingDirectory, c.imageTag)
+	if result := build.buildFunction(logger, config); result.ExitCode != 0 {
+		return config, result.ExitCode
+	}
+
+	logger.LogHeader1(fmt.Sprintf("Starting %s", config.GetImageTag()))
+	container, err := builders.StartFunctionContainer(ctx, config, c.env)
+	if err != nil {
+		logger.Error(err.Error())
+		return config, 1
+	}
+
+	server.replace(container)
+	return config, 0
+}
+
+// functionServer translates http requests into API Gateway proxy
+// events, invoking the current function container with each
+type functionServer struct {
+	container     *builders.FunctionContainer
+	logger        *ui.ZerologUi
+	mu            sync.RWMutex
+	payloadFormat string
+}
+
+// replace swaps in a new container once in-flight requests complete, removing the previous one
+func (s *functionServer) replace(container *builders.FunctionContainer) {
+	s.mu.Lock()
+	previous := s.container
+	s.container = container
+	s.mu.Unlock()
+
+	if previous != nil {
+		removeFunctionContainer(s.logger, previous)
+	}
+}
+
+// stop removes the current container
+func (s *functionServer) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.container != nil {
+		removeFunctionContainer(s.logger, s.container)
+		s.container = nil
+	}
+}
+
+func (s *functionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start := time.Now()
+	status := s.serve(w, r)
+	s.logger.Info(fmt.Sprintf("%s %s %d %s", r.Method, r.URL.RequestURI(), status, time.Since(start).Round(time.Millisecond)))
+}
+
+// serve invokes the function with the request, returning the status code written
+func (s *functionServer) serve(w http.ResponseWriter, r *http.Request) int {
+	if s.container == nil {
+		return writeGatewayError(w, http.StatusServiceUnavailable, "Service Unavailable")
+	}
+
+	event, err := builders.NewGatewayEvent(r, s.payloadFormat)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return writeGatewayError(w, http.StatusBadRequest, "Bad Request")
+	}
+
+	result, err := s.container.Invoke(r.Context(), event)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return writeGatewayError(w, http.StatusBadGateway, "Internal Server Error")
+	}
+
+	for _, line := range strings.Split(strings.TrimRight(result.Logs, "\n"), "\n") {
+		if line != "" {
+			s.logger.Output(line)
+		}
+	}
+
+	if result.FunctionError != "" {
+		s.logger.Error(fmt.Sprintf("Function returned an error: %s", string(result.Payload)))
+		return writeGatewayError(w, http.StatusBadGateway, "Internal Server Error")
+	}
+
+	recorder := &statusRecorder{ResponseWriter: w}
+	if err := builders.WriteGatewayResponse(recorder, result.Payload, s.payloadFormat); err != nil {
+		s.logger.Error(err.Error())
+		return writeGatewayError(w, http.StatusBadGateway, "Internal Server Error")
+	}
+
+	return recorder.status
+}
+
+// writeGatewayError writes an error response in the format used by API Gateway
+func writeGatewayError(w http.ResponseWriter, status int, message string) int {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "{\"message\":\"%s\"}", message)
+	return status
+}
+
+// statusRecorder records the status code written to a response
+type statusRecorder struct {
+	http.ResponseWriter
+
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"lambda-builder/builders"
+	"lambda-builder/io"
+	"lambda-builder/ui"
+
+	"github.com/google/uuid"
+	"github.com/josegonzalez/cli-skeleton/command"
+	"github.com/posener/complete"
+	flag "github.com/spf13/pflag"
+)
+
+type ShellCommand struct {
+	command.Meta
+
+	architecture     string
+	buildEnv         []string
+	builder          string
+	buildImage       string
+	engine           string
+	imageTag         string
+	reuseBuildImage  bool
+	workingDirectory string
+}
+
+func (c *ShellCommand) Name() string {
+	return "shell"
+}
+
+func (c *ShellCommand) Synopsis() string {
+	return "Starts a shell within the build image"
+}
+
+func (c *ShellCommand) Help() string {
+	return command.CommandHelp(c)
+}
+
+func (c *ShellCommand) Examples() map[string]string {
+	appName := os.Getenv("CLI_APP_NAME")
+	return map[string]string{
+		"Starts a shell in the build image with the current directory mounted": fmt.Sprintf("%s %s", appName, c.Name()),
+		"Starts a shell in the build image kept by a previous build":           fmt.Sprintf("%s %s --reuse-build-image", appName, c.Name()),
+	}
+}
+
+func (c *ShellCommand) Arguments() []command.Argument {
+	args := []command.Argument{}
+	return args
+}
+
+func (c *ShellCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *ShellCommand) ParsedArguments(args []string) (map[string]command.Argument, error) {
+	return command.ParseArguments(args, c.Arguments())
+}
+
+func (c *ShellCommand) FlagSet() *flag.FlagSet {
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+
+	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
+	f.BoolVar(&c.reuseBuildImage, "reuse-build-image", false, "use the build image kept by a previous build with --keep-build-image")
+	f.StringVar(&c.architecture, "arch", "", "set the lambda architecture to build for (x86_64 or arm64)")
+	f.StringVar(&c.builder, "builder", "", "set the builder to use")
+	f.StringVar(&c.buildImage, "build-image", "", "set the build-image to use")
+	f.StringVar(&c.engine, "engine", "", "set the container engine to use")
+	f.StringVar(&c.workingDirectory, "working-directory", workingDirectory, "working directory")
+	f.StringVarP(&c.imageTag, "tag", "t", "", "name and optionally a tag in the 'name:tag' format of the built image")
+	f.StringArrayVar(&c.buildEnv, "build-env", []string{}, "environment variables to be set for the build context")
+	return f
+}
+
+func (c *ShellCommand) AutocompleteFlags() complete.Flags {
+	return command.MergeAutocompleteFlags(
+		c.Meta.AutocompleteFlags(command.FlagSetClient),
+		complete.Flags{
+			"--arch":              complete.PredictSet(builders.Architectures...),
+			"--build-env":         complete.PredictAnything,
+			"--build-image":       complete.PredictAnything,
+			"--builder":           complete.PredictSet(builders.BuilderNames()...),
+			"--engine":            complete.PredictSet(builders.ContainerEngines...),
+			"--reuse-build-image": complete.PredictNothing,
+			"-t":                  complete.PredictAnything,
+			"--tag":               complete.PredictAnything,
+			"--working-directory": complete.PredictAnything,
+		},
+	)
+}
+
+func (c *ShellCommand) Run(args []string) int {
+	flags := c.FlagSet()
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		c.Ui.Error(err.Error())
+		c.Ui.Error(command.CommandErrorText(c))
+		return 1
+	}
+
+	var err error
+	c.workingDirectory, err = filepath.Abs(c.workingDirectory)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	logger, ok := c.Ui.(*ui.ZerologUi)
+	if !ok {
+		c.Ui.Error("Unable to fetch logger from cli")
+		return 1
+	}
+
+	if !io.FolderExists(c.workingDirectory) {
+		c.Ui.Error(fmt.Sprintf("Working directory '%s' does not exist", c.workingDirectory))
+		return 1
+	}
+
+	config := builders.Config{
+		Architecture:      c.architecture,
+		BuildEnv:          c.buildEnv,
+		Builder:           c.builder,
+		BuilderBuildImage: c.buildImage,
+		Engine:            c.engine,
+		Identifier:        uuid.New().String(),
+		ImageTag:          c.imageTag,
+		Logger:            logger,
+		ReuseBuildImage:   c.reuseBuildImage,
+		WorkingDirectory:  c.workingDirectory,
+	}
+
+	logger.LogHeader1("Detecting builder")
+	builder, err := builders.DetectBuilder(config)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	c.Ui.Info(fmt.Sprintf("Detected %s builder", builder.Name()))
+
+	if err := builder.Shell(); err != nil {
+		var exitErr *builders.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode
+		}
+
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
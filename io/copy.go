@@ -0,0 +1,131 @@
+package io
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// CopyDirectory copies the contents of src into dst, skipping any
+// path matched by the ignore matcher. Symlinks are copied as symlinks.
+func CopyDirectory(src string, dst string, matcher *IgnoreMatcher) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, rel)
+		if rel == "." {
+			return os.MkdirAll(target, 0755)
+		}
+
+		if matcher != nil && matcher.Matches(rel) {
+			// directories are walked when patterns may re-include their contents
+			if info.IsDir() && !matcher.HasExclusions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		}
+
+		return nil
+	})
+}
+
+// RemoveMatching removes each file within directory matched by the ignore matcher,
+// along with any matched directory left empty, returning the number of files
+// removed and their combined size in bytes
+func RemoveMatching(directory string, matcher *IgnoreMatcher) (int, int64, error) {
+	count := 0
+	var size int64
+	directories := []string{}
+	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+
+		if rel == "." || !matcher.Matches(rel) {
+			return nil
+		}
+
+		if info.IsDir() {
+			directories = append(directories, path)
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+
+		count++
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return count, size, err
+	}
+
+	// directories are removed deepest first, keeping any holding re-included files
+	for i := len(directories) - 1; i >= 0; i-- {
+		entries, err := os.ReadDir(directories[i])
+		if err != nil {
+			return count, size, err
+		}
+
+		if len(entries) == 0 {
+			if err := os.Remove(directories[i]); err != nil {
+				return count, size, err
+			}
+		}
+	}
+
+	return count, size, nil
+}
+
+func copyFile(src string, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,169 @@
+package io
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreMatcher matches paths against a list of patterns
+// using the same syntax as a .dockerignore file
+type IgnoreMatcher struct {
+	patterns      []ignorePattern
+	hasExclusions bool
+}
+
+type ignorePattern struct {
+	exclusion bool
+	pattern   string
+	regexp    *regexp.Regexp
+}
+
+// NewIgnoreMatcher compiles the given patterns into an IgnoreMatcher
+func NewIgnoreMatcher(patterns []string) (*IgnoreMatcher, error) {
+	m := &IgnoreMatcher{}
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" || strings.HasPrefix(p, "#") {
+			continue
+		}
+
+		exclusion := false
+		if strings.HasPrefix(p, "!") {
+			exclusion = true
+			p = strings.TrimSpace(p[1:])
+		}
+
+		p = strings.TrimPrefix(path.Clean(filepath.ToSlash(p)), "/")
+		if p == "." || p == "" {
+			continue
+		}
+
+		re, err := ignorePatternToRegexp(p)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing ignore pattern '%s': %w", p, err)
+		}
+
+		if exclusion {
+			m.hasExclusions = true
+		}
+
+		m.patterns = append(m.patterns, ignorePattern{
+			exclusion: exclusion,
+			pattern:   p,
+			regexp:    re,
+		})
+	}
+
+	return m, nil
+}
+
+// HasExclusions returns true if any pattern re-includes paths via `!`
+func (m *IgnoreMatcher) HasExclusions() bool {
+	return m.hasExclusions
+}
+
+// Matches returns true if the slash-separated relative path, or
+// any of its parent directories, is ignored
+func (m *IgnoreMatcher) Matches(file string) bool {
+	file = strings.TrimPrefix(path.Clean(filepath.ToSlash(file)), "/")
+	parents := []string{}
+	parts := strings.Split(file, "/")
+	for i := 1; i < len(parts); i++ {
+		parents = append(parents, strings.Join(parts[:i], "/"))
+	}
+
+	matched := false
+	for _, p := range m.patterns {
+		if p.exclusion != matched {
+			continue
+		}
+
+		match := p.regexp.MatchString(file)
+		if !match {
+			for _, parent := range parents {
+				if p.regexp.MatchString(parent) {
+					match = true
+					break
+				}
+			}
+		}
+
+		if match {
+			matched = !p.exclusion
+		}
+	}
+
+	return matched
+}
+
+// ReadIgnoreFile reads the patterns from an ignore file, returning
+// an empty list if the file does not exist
+func ReadIgnoreFile(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	patterns := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+
+	return patterns, scanner.Err()
+}
+
+func ignorePatternToRegexp(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		ch := pattern[i]
+		switch ch {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					// "**/" matches zero or more directories
+					i++
+					sb.WriteString("(.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated character class")
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	sb.WriteString("$")
+
+	return regexp.Compile(sb.String())
+}
@@ -12,6 +12,16 @@ func FileExistsInDirectory(directory string, filename string) bool {
 	return false
 }
 
+// ExecutableExistsInDirectory returns true if the file exists and is executable by anyone
+func ExecutableExistsInDirectory(directory string, filename string) bool {
+	info, err := os.Stat(filepath.Join(directory, filename))
+	if err != nil {
+		return false
+	}
+
+	return !info.IsDir() && info.Mode().Perm()&0111 != 0
+}
+
 func FolderExists(directory string) bool {
 	info, err := os.Stat(directory)
 	if err != nil {
@@ -0,0 +1,296 @@
+package io
+
+import (
+	"archive/zip"
+	"sort"
+	"strings"
+)
+
+// ZipPathSize describes the combined size of the files below a path within a zip file
+type ZipPathSize struct {
+	// CompressedSize is the combined compressed size of the files in bytes
+	CompressedSize int64 `json:"compressed_size"`
+
+	// CompressionRatio is the uncompressed size divided by the compressed size
+	CompressionRatio float64 `json:"compression_ratio"`
+
+	// FileCount is the number of files below the path
+	FileCount int `json:"file_count"`
+
+	// Path is the path within the zip file
+	Path string `json:"path"`
+
+	// UncompressedSize is the combined uncompressed size of the files in bytes
+	UncompressedSize int64 `json:"uncompressed_size"`
+}
+
+// ZipTreeNode is a file or directory within a zip file, sized by everything below it
+type ZipTreeNode struct {
+	ZipPathSize
+
+	// Children are the files and directories directly below the node, largest first
+	Children []*ZipTreeNode `json:"children,omitempty"`
+}
+
+// ZipDuplicate describes files within a zip file that share identical contents
+type ZipDuplicate struct {
+	// Paths are the paths of each copy of the file
+	Paths []string `json:"paths"`
+
+	// Size is the uncompressed size of a single copy in bytes
+	Size int64 `json:"size"`
+
+	// WastedSize is the uncompressed size of all but one copy in bytes
+	WastedSize int64 `json:"wasted_size"`
+}
+
+// ZipAnalysis describes where the size of a zip file comes from
+type ZipAnalysis struct {
+	ZipPathSize
+
+	// Duplicates lists files stored more than once, most wasteful first
+	Duplicates []ZipDuplicate `json:"duplicates"`
+
+	// Files lists every file within the zip file, largest first
+	Files []ZipPathSize `json:"files"`
+
+	// Packages lists the combined size of each package or top-level directory, largest first
+	Packages []ZipPathSize `json:"packages"`
+
+	// Size is the size of the zip file itself in bytes
+	Size int64 `json:"size"`
+
+	// Tree is the directory tree of the zip file
+	Tree *ZipTreeNode `json:"tree"`
+}
+
+// packageDirectories are directories whose children are each an installed package
+var packageDirectories = []string{"gems", "node_modules", "site-packages", "vendor"}
+
+// ZipUncompressedSize returns the combined uncompressed size of the files within a zip file
+func ZipUncompressedSize(path string) (int64, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	var size int64
+	for _, f := range r.File {
+		size += int64(f.UncompressedSize64)
+	}
+
+	return size, nil
+}
+
+// ZipLargestPackages returns the paths contributing the most to the uncompressed
+// size of a zip file, largest first. Files within an installed package - such as
+// node_modules/lodash or site-packages/numpy - are grouped by package, while
+// other files are grouped by their top-level directory
+func ZipLargestPackages(path string, count int) ([]ZipPathSize, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	paths := groupZipFiles(r.File, packagePath)
+	if len(paths) > count {
+		paths = paths[:count]
+	}
+
+	return paths, nil
+}
+
+// AnalyzeZip reads the entries of a zip file, breaking its size down by file,
+// package and directory, and finding files that are stored more than once
+func AnalyzeZip(path string) (ZipAnalysis, error) {
+	analysis := ZipAnalysis{
+		Duplicates: []ZipDuplicate{},
+		ZipPathSize: ZipPathSize{
+			Path: path,
+		},
+	}
+
+	size, err := FileSize(path)
+	if err != nil {
+		return analysis, err
+	}
+	analysis.Size = size
+
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return analysis, err
+	}
+	defer r.Close()
+
+	analysis.Files = groupZipFiles(r.File, func(name string) string {
+		return strings.TrimPrefix(name, "/")
+	})
+	analysis.Packages = groupZipFiles(r.File, packagePath)
+	analysis.Tree = newZipTree(analysis.Files)
+	analysis.CompressedSize = analysis.Tree.CompressedSize
+	analysis.CompressionRatio = analysis.Tree.CompressionRatio
+	analysis.FileCount = analysis.Tree.FileCount
+	analysis.UncompressedSize = analysis.Tree.UncompressedSize
+
+	// the crc32 and size of an entry identify its contents without decompressing it
+	type contents struct {
+		crc32 uint32
+		size  uint64
+	}
+	copies := map[contents][]string{}
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || f.UncompressedSize64 == 0 {
+			continue
+		}
+
+		key := contents{crc32: f.CRC32, size: f.UncompressedSize64}
+		copies[key] = append(copies[key], strings.TrimPrefix(f.Name, "/"))
+	}
+
+	for key, paths := range copies {
+		if len(paths) < 2 {
+			continue
+		}
+
+		sort.Strings(paths)
+		analysis.Duplicates = append(analysis.Duplicates, ZipDuplicate{
+			Paths:      paths,
+			Size:       int64(key.size),
+			WastedSize: int64(key.size) * int64(len(paths)-1),
+		})
+	}
+
+	sort.Slice(analysis.Duplicates, func(i, j int) bool {
+		if analysis.Duplicates[i].WastedSize == analysis.Duplicates[j].WastedSize {
+			return analysis.Duplicates[i].Paths[0] < analysis.Duplicates[j].Paths[0]
+		}
+		return analysis.Duplicates[i].WastedSize > analysis.Duplicates[j].WastedSize
+	})
+
+	return analysis, nil
+}
+
+// groupZipFiles combines the sizes of the files within a zip file by the
+// path returned for each, returning the groups largest first
+func groupZipFiles(files []*zip.File, key func(name string) string) []ZipPathSize {
+	sizes := map[string]*ZipPathSize{}
+	for _, f := range files {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		path := key(f.Name)
+		if _, ok := sizes[path]; !ok {
+			sizes[path] = &ZipPathSize{Path: path}
+		}
+
+		sizes[path].CompressedSize += int64(f.CompressedSize64)
+		sizes[path].FileCount++
+		sizes[path].UncompressedSize += int64(f.UncompressedSize64)
+	}
+
+	paths := []ZipPathSize{}
+	for _, size := range sizes {
+		size.CompressionRatio = compressionRatio(size.CompressedSize, size.UncompressedSize)
+		paths = append(paths, *size)
+	}
+
+	sortZipPathSizes(paths)
+	return paths
+}
+
+// newZipTree builds a directory tree from the files within a zip file
+func newZipTree(files []ZipPathSize) *ZipTreeNode {
+	root := &ZipTreeNode{ZipPathSize: ZipPathSize{Path: "."}}
+	nodes := map[string]*ZipTreeNode{}
+	for _, file := range files {
+		parent := root
+		parts := strings.Split(file.Path, "/")
+		for i := range parts {
+			path := strings.Join(parts[:i+1], "/")
+			node, ok := nodes[path]
+			if !ok {
+				node = &ZipTreeNode{ZipPathSize: ZipPathSize{Path: path}}
+				nodes[path] = node
+				parent.Children = append(parent.Children, node)
+			}
+
+			addZipPathSize(parent, file)
+			parent = node
+		}
+
+		addZipPathSize(parent, file)
+	}
+
+	sortZipTree(root)
+	return root
+}
+
+// addZipPathSize adds the size of a file to a node of the directory tree
+func addZipPathSize(node *ZipTreeNode, file ZipPathSize) {
+	node.CompressedSize += file.CompressedSize
+	node.FileCount += file.FileCount
+	node.UncompressedSize += file.UncompressedSize
+	node.CompressionRatio = compressionRatio(node.CompressedSize, node.UncompressedSize)
+}
+
+// sortZipTree sorts the children of each node in the tree, largest first
+func sortZipTree(node *ZipTreeNode) {
+	sort.Slice(node.Children, func(i, j int) bool {
+		a, b := node.Children[i], node.Children[j]
+		if a.UncompressedSize == b.UncompressedSize {
+			return a.Path < b.Path
+		}
+		return a.UncompressedSize > b.UncompressedSize
+	})
+
+	for _, child := range node.Children {
+		sortZipTree(child)
+	}
+}
+
+// sortZipPathSizes sorts paths largest first, then by path
+func sortZipPathSizes(paths []ZipPathSize) {
+	sort.Slice(paths, func(i, j int) bool {
+		if paths[i].UncompressedSize == paths[j].UncompressedSize {
+			return paths[i].Path < paths[j].Path
+		}
+		return paths[i].UncompressedSize > paths[j].UncompressedSize
+	})
+}
+
+// compressionRatio returns the uncompressed size divided by the compressed size
+func compressionRatio(compressedSize int64, uncompressedSize int64) float64 {
+	if compressedSize == 0 {
+		return 0
+	}
+
+	return float64(uncompressedSize) / float64(compressedSize)
+}
+
+// packagePath returns the path of the package or top-level directory a file belongs to
+func packagePath(name string) string {
+	parts := strings.Split(strings.TrimPrefix(name, "/"), "/")
+	end := 1
+	for i := 0; i < len(parts)-1; i++ {
+		for _, directory := range packageDirectories {
+			if parts[i] != directory {
+				continue
+			}
+
+			end = i + 2
+			// scoped node packages are nested a level deeper
+			if directory == "node_modules" && strings.HasPrefix(parts[i+1], "@") && i+2 < len(parts)-1 {
+				end = i + 3
+			}
+		}
+	}
+
+	if end > len(parts) {
+		end = len(parts)
+	}
+
+	return strings.Join(parts[:end], "/")
+}
@@ -0,0 +1,87 @@
+package io
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WatchDirectory polls directory at the given interval until the context is
+// done, calling onChange whenever a file not matched by the ignore matcher is
+// added, removed, or modified. Changes are debounced, with onChange called
+// once the directory is unchanged for a full interval, so a burst of writes
+// results in a single call. onChange is not called concurrently, and changes
+// made while it runs are reported once it returns
+func WatchDirectory(ctx context.Context, directory string, matcher *IgnoreMatcher, interval time.Duration, onChange func()) error {
+	previous, err := directoryFingerprint(directory, matcher)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	pending := false
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := directoryFingerprint(directory, matcher)
+		if err != nil {
+			return err
+		}
+
+		if current != previous {
+			previous = current
+			pending = true
+			continue
+		}
+
+		if pending {
+			pending = false
+			onChange()
+		}
+	}
+}
+
+// directoryFingerprint returns a digest of the path, size, mode, and
+// modification time of each file within directory not matched by the matcher
+func directoryFingerprint(directory string, matcher *IgnoreMatcher) (string, error) {
+	hash := sha256.New()
+	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// files removed mid-walk are picked up by the next poll
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		rel, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+
+		if rel != "." && matcher != nil && matcher.Matches(rel) {
+			if info.IsDir() && !matcher.HasExclusions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		fmt.Fprintf(hash, "%s\x00%d\x00%s\x00%d\n", filepath.ToSlash(rel), info.Size(), info.Mode(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
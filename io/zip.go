@@ -0,0 +1,187 @@
+package io
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DefaultZipModTime is the modification time given to zip entries when
+// SOURCE_DATE_EPOCH is unset, and the earliest time a zip file can represent
+var DefaultZipModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ZipModTime returns the modification time to give zip entries,
+// honoring the SOURCE_DATE_EPOCH environment variable
+func ZipModTime() (time.Time, error) {
+	value := os.Getenv("SOURCE_DATE_EPOCH")
+	if value == "" {
+		return DefaultZipModTime, nil
+	}
+
+	epoch, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH: %s", value)
+	}
+
+	modTime := time.Unix(epoch, 0).UTC()
+	if modTime.Before(DefaultZipModTime) {
+		return DefaultZipModTime, nil
+	}
+
+	return modTime, nil
+}
+
+// WriteDeterministicZip writes the contents of directory to a zip file at destination.
+// Entries are sorted by path, share a single modification time, and have their
+// modes normalized so that identical inputs produce byte-identical zip files
+func WriteDeterministicZip(directory string, destination string, modTime time.Time) error {
+	paths := []string{}
+	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path != directory {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		return filepath.ToSlash(paths[i]) < filepath.ToSlash(paths[j])
+	})
+
+	f, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+
+	w := zip.NewWriter(f)
+	w.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(out, flate.DefaultCompression)
+	})
+
+	for _, path := range paths {
+		if err := writeZipEntry(w, directory, path, modTime); err != nil {
+			w.Close()
+			f.Close()
+			return err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func writeZipEntry(w *zip.Writer, directory string, path string, modTime time.Time) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(directory, path)
+	if err != nil {
+		return err
+	}
+
+	header := &zip.FileHeader{
+		Name:     filepath.ToSlash(rel),
+		Method:   zip.Deflate,
+		Modified: modTime,
+	}
+
+	switch {
+	case info.IsDir():
+		header.Name += "/"
+		header.Method = zip.Store
+		header.SetMode(os.ModeDir | 0755)
+		_, err := w.CreateHeader(header)
+		return err
+	case info.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+
+		header.Method = zip.Store
+		header.SetMode(os.ModeSymlink | 0777)
+		entry, err := w.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		_, err = entry.Write([]byte(link))
+		return err
+	case !info.Mode().IsRegular():
+		return nil
+	}
+
+	mode := os.FileMode(0644)
+	if info.Mode().Perm()&0111 != 0 {
+		mode = 0755
+	}
+	header.SetMode(mode)
+
+	entry, err := w.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	_, err = io.Copy(entry, in)
+	return err
+}
+
+// FileSHA256 returns the hex encoded SHA-256 digest of a file
+func FileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ZipFileCount returns the number of files within a zip file, excluding directories
+func ZipFileCount(path string) (int, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	count := 0
+	for _, f := range r.File {
+		if !f.FileInfo().IsDir() {
+			count++
+		}
+	}
+
+	return count, nil
+}
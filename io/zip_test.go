@@ -0,0 +1,139 @@
+package io
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeZipTestTree writes an app with a nested directory and an executable,
+// giving every file the mode and modification time specified
+func writeZipTestTree(t *testing.T, directory string, mode os.FileMode, modTime time.Time) {
+	t.Helper()
+
+	files := map[string]string{
+		"bootstrap":          "#!/bin/sh\necho hello\n",
+		"function.py":        "def handler(event, context):\n    return 'Hello World!'\n",
+		"lib/package/mod.py": "VALUE = 1\n",
+	}
+
+	for name, contents := range files {
+		path := filepath.Join(directory, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		fileMode := mode
+		if name == "bootstrap" {
+			fileMode = 0755
+		}
+
+		if err := os.Chmod(path, fileMode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		return os.Chtimes(path, modTime, modTime)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteDeterministicZip(t *testing.T) {
+	modTime, err := ZipModTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := t.TempDir()
+	first, second := t.TempDir(), t.TempDir()
+	writeZipTestTree(t, first, 0644, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	writeZipTestTree(t, second, 0600, time.Date(2024, 6, 15, 12, 30, 0, 0, time.UTC))
+
+	firstZip := filepath.Join(output, "first.zip")
+	if err := WriteDeterministicZip(first, firstZip, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	secondZip := filepath.Join(output, "second.zip")
+	if err := WriteDeterministicZip(second, secondZip, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	firstBytes, err := os.ReadFile(firstZip)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secondBytes, err := os.ReadFile(secondZip)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(firstBytes, secondBytes) {
+		t.Fatal("expected zip files of identical trees to be byte-identical")
+	}
+
+	firstSHA256, err := FileSHA256(firstZip)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secondSHA256, err := FileSHA256(secondZip)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if firstSHA256 != secondSHA256 {
+		t.Fatalf("expected matching checksums, got %s and %s", firstSHA256, secondSHA256)
+	}
+
+	r, err := zip.OpenReader(secondZip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	names := []string{}
+	for _, f := range r.File {
+		names = append(names, f.Name)
+		if !f.Modified.Equal(modTime) {
+			t.Errorf("expected %s to be modified at %s, got %s", f.Name, modTime, f.Modified)
+		}
+
+		switch f.Name {
+		case "bootstrap":
+			if f.Mode().Perm()&0111 == 0 {
+				t.Errorf("expected bootstrap to be executable, got mode %s", f.Mode())
+			}
+		case "function.py", "lib/package/mod.py":
+			if f.Mode().Perm()&0111 != 0 {
+				t.Errorf("expected %s not to be executable, got mode %s", f.Name, f.Mode())
+			}
+		}
+	}
+
+	expected := []string{"bootstrap", "function.py", "lib/", "lib/package/", "lib/package/mod.py"}
+	if len(names) != len(expected) {
+		t.Fatalf("expected entries %v, got %v", expected, names)
+	}
+
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("expected entries %v, got %v", expected, names)
+		}
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
+	"lambda-builder/builders"
 	"lambda-builder/commands"
 	"lambda-builder/ui"
 
@@ -24,11 +26,28 @@ func main() {
 
 // Executes the specified subcommand
 func Run(args []string) int {
+	args, logFormat, err := parseLogFormat(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing arguments: %s\n", err.Error())
+		return 1
+	}
+
+	if err := ui.SetLogFormat(logFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing arguments: %s\n", err.Error())
+		return 1
+	}
+
 	ctx := context.Background()
 	commandMeta := command.SetupRun(ctx, AppName, Version, args)
 	commandMeta.Ui = ui.ZerologUiWithFields(commandMeta.Ui, make(map[string]interface{}, 0))
+
+	// an invalid builder definition only skips that builder, as most
+	// commands can still be used without it
+	for _, err := range builders.LoadBuilderDefinitions(builders.BuilderDefinitionsDirectory()) {
+		commandMeta.Ui.Warn(fmt.Sprintf("Skipping builder definition: %s", err.Error()))
+	}
 	c := cli.NewCLI(AppName, Version)
-	c.Args = os.Args[1:]
+	c.Args = args
 	c.Commands = command.Commands(ctx, commandMeta, Commands)
 	exitCode, err := c.Run()
 	if err != nil {
@@ -39,12 +58,57 @@ func Run(args []string) int {
 	return exitCode
 }
 
+// parseLogFormat removes the global --log-format flag from the args,
+// returning the remaining args and the selected log format
+func parseLogFormat(args []string) ([]string, string, error) {
+	logFormat := ui.LogFormatAuto
+	remaining := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			remaining = append(remaining, args[i:]...)
+			break
+		}
+
+		if arg == "--log-format" {
+			if i+1 >= len(args) {
+				return nil, "", fmt.Errorf("flag needs an argument: --log-format")
+			}
+
+			logFormat = args[i+1]
+			i++
+			continue
+		}
+
+		if strings.HasPrefix(arg, "--log-format=") {
+			logFormat = strings.TrimPrefix(arg, "--log-format=")
+			continue
+		}
+
+		remaining = append(remaining, arg)
+	}
+
+	return remaining, logFormat, nil
+}
+
 // Returns a list of implemented commands
 func Commands(ctx context.Context, meta command.Meta) map[string]cli.CommandFactory {
 	return map[string]cli.CommandFactory{
+		"analyze": func() (cli.Command, error) {
+			return &commands.AnalyzeCommand{Meta: meta}, nil
+		},
 		"build": func() (cli.Command, error) {
 			return &commands.BuildCommand{Meta: meta}, nil
 		},
+		"invoke": func() (cli.Command, error) {
+			return &commands.InvokeCommand{Meta: meta}, nil
+		},
+		"serve": func() (cli.Command, error) {
+			return &commands.ServeCommand{Meta: meta}, nil
+		},
+		"shell": func() (cli.Command, error) {
+			return &commands.ShellCommand{Meta: meta}, nil
+		},
 		"version": func() (cli.Command, error) {
 			return &command.VersionCommand{Meta: meta}, nil
 		},
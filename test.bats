@@ -59,7 +59,7 @@ teardown_file() {
   run $LAMBDA_BUILDER_BIN build --working-directory tests/lambda.yml-invalid-image
   echo "output: $output"
   echo "status: $status"
-  [[ "$status" -eq 1 ]]
+  [[ "$status" -eq 2 ]]
 }
 
 @test "[build] lambda.yml-nonexistent-builder" {
@@ -69,6 +69,20 @@ teardown_file() {
   [[ "$status" -eq 1 ]]
 }
 
+@test "[build] maven" {
+  run $LAMBDA_BUILDER_BIN build --working-directory tests/maven
+  echo "output: $output"
+  echo "status: $status"
+  [[ "$status" -eq 0 ]]
+
+  run unzip -l tests/maven/lambda.zip
+  echo "output: $output"
+  echo "status: $status"
+  [[ "$status" -eq 0 ]]
+  [[ "$output" == *"example/Handler.class"* ]]
+  [[ "$output" == *"lib/aws-lambda-java-core-"*".jar"* ]]
+}
+
 @test "[build] npm" {
   run $LAMBDA_BUILDER_BIN build --working-directory tests/npm
   echo "output: $output"
@@ -97,6 +111,15 @@ teardown_file() {
   [[ "$status" -eq 0 ]]
 }
 
+@test "[build] pip layer" {
+  run $LAMBDA_BUILDER_BIN build --working-directory tests/pip --layer --layer-with-function
+  echo "output: $output"
+  echo "status: $status"
+  [[ "$status" -eq 0 ]]
+  [[ -f tests/pip/layer.zip ]]
+  [[ -f tests/pip/lambda.zip ]]
+}
+
 @test "[build] pip-runtime" {
   run $LAMBDA_BUILDER_BIN build --working-directory tests/pip-runtime
   echo "output: $output"
@@ -118,9 +141,77 @@ teardown_file() {
   [[ "$status" -eq 0 ]]
 }
 
+@test "[build] provided" {
+  run $LAMBDA_BUILDER_BIN build --working-directory tests/provided
+  echo "output: $output"
+  echo "status: $status"
+  [[ "$status" -eq 0 ]]
+
+  run unzip -Z tests/provided/lambda.zip bootstrap
+  echo "output: $output"
+  echo "status: $status"
+  [[ "$status" -eq 0 ]]
+  [[ "$output" == "-rwx"* ]]
+}
+
 @test "[build] ruby" {
   run $LAMBDA_BUILDER_BIN build --working-directory tests/ruby
   echo "output: $output"
   echo "status: $status"
   [[ "$status" -eq 0 ]]
 }
+
+@test "[build] rust" {
+  run $LAMBDA_BUILDER_BIN build --working-directory tests/rust
+  echo "output: $output"
+  echo "status: $status"
+  [[ "$status" -eq 0 ]]
+
+  run unzip -Z tests/rust/lambda.zip bootstrap
+  echo "output: $output"
+  echo "status: $status"
+  [[ "$status" -eq 0 ]]
+  [[ "$output" == "-rwx"* ]]
+}
+
+@test "[invoke] handler override" {
+  run $LAMBDA_BUILDER_BIN invoke --working-directory tests/pip --build --handler function.override_handler
+  echo "output: $output"
+  echo "status: $status"
+  [[ "$status" -eq 0 ]]
+  [[ "$output" == *"Invoking function.override_handler"* ]]
+  [[ "$output" == *"Hello Override!"* ]]
+}
+
+@test "[invoke] handler override of an existing image" {
+  run $LAMBDA_BUILDER_BIN build --working-directory tests/pip --generate-image
+  echo "output: $output"
+  echo "status: $status"
+  [[ "$status" -eq 0 ]]
+
+  run $LAMBDA_BUILDER_BIN invoke --working-directory tests/pip --handler function.override_handler
+  echo "output: $output"
+  echo "status: $status"
+  [[ "$status" -eq 0 ]]
+  [[ "$output" == *"Invoking function.override_handler"* ]]
+  [[ "$output" == *"Hello Override!"* ]]
+}
+
+@test "[serve] handler override" {
+  $LAMBDA_BUILDER_BIN serve --working-directory tests/pip --no-watch --listen 127.0.0.1:3999 --handler function.override_handler >"$BATS_TEST_TMPDIR/serve.log" 2>&1 3>&- &
+  local pid="$!"
+
+  for _ in $(seq 1 120); do
+    if output="$(curl -sf http://127.0.0.1:3999/)"; then
+      break
+    fi
+    sleep 1
+  done
+
+  kill -INT "$pid"
+  wait "$pid" || true
+  echo "output: $output"
+  cat "$BATS_TEST_TMPDIR/serve.log"
+  [[ "$output" == "Hello Override!" ]]
+  grep -q "Serving function.override_handler" "$BATS_TEST_TMPDIR/serve.log"
+}
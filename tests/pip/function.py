@@ -5,3 +5,7 @@ def handler(event, context):
     response = requests.get("https://example.com")
     print(response.text)
     return "Hello World!"
+
+
+def override_handler(event, context):
+    return {"statusCode": 200, "body": "Hello Override!"}
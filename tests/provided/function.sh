@@ -0,0 +1,5 @@
+handler() {
+  EVENT_DATA="$1"
+  echo "$EVENT_DATA" 1>&2
+  echo "Hello World!"
+}
@@ -0,0 +1,16 @@
+use lambda_runtime::{service_fn, Error, LambdaEvent};
+use serde::Deserialize;
+
+#[derive(Deserialize)]
+struct MyEvent {
+    name: String,
+}
+
+async fn handle_request(event: LambdaEvent<MyEvent>) -> Result<String, Error> {
+    Ok(format!("Hello {}!", event.payload.name))
+}
+
+#[tokio::main]
+async fn main() -> Result<(), Error> {
+    lambda_runtime::run(service_fn(handle_request)).await
+}
@@ -105,6 +105,15 @@ func (w HumanWriter) Write(p []byte) (n int, err error) {
 		return n, fmt.Errorf("cannot decode event: %s", err)
 	}
 
+	// the phase is only of use to structured log consumers
+	delete(evt, "phase")
+
+	// lines logged on behalf of a function are prefixed with its name
+	if function, ok := evt["function"].(string); ok {
+		delete(evt, "function")
+		buf.WriteString(colorize(fmt.Sprintf("[%s] ", function), colorCyan, w.NoColor))
+	}
+
 	val, ok := evt[zerolog.LevelFieldName].(string)
 	if !ok {
 		buf.WriteString("       ")
@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// LineWriter is an io.Writer that logs each line written to it
+// as an event, allowing command output to be routed through a logger.
+// Lines using the `=====> `, `-----> `, and ` !     ` prefixes of the
+// build scripts are logged as headers and warnings respectively
+type LineWriter struct {
+	// Transform, when set, is applied to each line before it is logged
+	Transform func(line string) string
+
+	buf    []byte
+	logger *ZerologUi
+	mu     sync.Mutex
+}
+
+// NewLineWriter returns a LineWriter that logs to the given logger
+func NewLineWriter(logger *ZerologUi) *LineWriter {
+	return &LineWriter{logger: logger}
+}
+
+// Write logs each complete line, buffering any partial line until
+// the rest of it is written or the writer is flushed
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		w.logLine(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush logs any partial line remaining in the buffer
+func (w *LineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.logLine(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (w *LineWriter) logLine(line string) {
+	if w.Transform != nil {
+		line = w.Transform(line)
+	}
+
+	line = strings.TrimRight(line, "\r\n\t ")
+	switch {
+	case strings.TrimSpace(line) == "":
+		return
+	case strings.HasPrefix(line, "=====> "):
+		w.logger.LogHeader1(strings.TrimPrefix(line, "=====> "))
+	case strings.HasPrefix(line, "-----> "):
+		w.logger.LogHeader2(strings.TrimPrefix(line, "-----> "))
+	case strings.HasPrefix(line, " !     "):
+		w.logger.Warn(strings.TrimPrefix(line, " !     "))
+	default:
+		w.logger.Info(strings.TrimPrefix(line, "       "))
+	}
+}
@@ -1,6 +1,8 @@
 package ui
 
 import (
+	"fmt"
+	"io"
 	"os"
 
 	"github.com/mattn/go-isatty"
@@ -8,8 +10,48 @@ import (
 	"github.com/rs/zerolog"
 )
 
+const (
+	// LogFormatAuto writes human-readable logs to a terminal and json otherwise
+	LogFormatAuto = "auto"
+
+	// LogFormatHuman writes human-readable logs
+	LogFormatHuman = "human"
+
+	// LogFormatJSON writes each log event as a json object
+	LogFormatJSON = "json"
+)
+
+// LogFormats is the list of supported log formats
+var LogFormats = []string{LogFormatAuto, LogFormatHuman, LogFormatJSON}
+
 var isTerminal bool = false
 
+var logFormat = LogFormatAuto
+
+// SetLogFormat sets the format used by loggers created afterwards
+func SetLogFormat(format string) error {
+	for _, f := range LogFormats {
+		if f == format {
+			logFormat = format
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unsupported log format: %s", format)
+}
+
+// newLogWriter returns the writer that renders log events to out
+func newLogWriter(out io.Writer) io.Writer {
+	if logFormat == LogFormatJSON || (logFormat == LogFormatAuto && !isTerminal) {
+		return out
+	}
+
+	return HumanWriter{
+		NoColor: !isTerminal || os.Getenv("NO_COLOR") != "",
+		Out:     out,
+	}
+}
+
 type ZerologUi struct {
 	StderrLogger   zerolog.Logger
 	StdoutLogger   zerolog.Logger
@@ -73,8 +115,8 @@ func (u *ZerologUi) Fields(newFields map[string]interface{}) *ZerologUi {
 
 func ZerologUiWithFields(ui cli.Ui, fields map[string]interface{}) *ZerologUi {
 	return &ZerologUi{
-		StderrLogger:   zerolog.New(HumanWriter{Out: os.Stderr}).With().Fields(fields).Timestamp().Logger(),
-		StdoutLogger:   zerolog.New(HumanWriter{Out: os.Stdout}).With().Fields(fields).Timestamp().Logger(),
+		StderrLogger:   zerolog.New(newLogWriter(os.Stderr)).With().Fields(fields).Timestamp().Logger(),
+		StdoutLogger:   zerolog.New(newLogWriter(os.Stdout)).With().Fields(fields).Timestamp().Logger(),
 		OriginalFields: fields,
 		Ui:             ui,
 	}